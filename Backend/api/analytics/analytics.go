@@ -0,0 +1,141 @@
+// Package analytics records short link clicks off the request path.
+//
+// Handlers hand events to Record, which never blocks; a background worker
+// batches them into Redis DB 5 (and bumps the click counter on the link
+// record in DB 0) with one pipeline per batch.
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	queueSize     = 4096
+	batchSize     = 200
+	flushInterval = time.Second
+
+	maxEvents = 1000                 // raw events kept per link
+	retention = 400 * 24 * time.Hour // analytics keys outlive the link by this much at most
+)
+
+// Event is a single resolution of a short link.
+type Event struct {
+	ShortID   string    `json:"-"`
+	At        time.Time `json:"at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Visitor   string    `json:"visitor"` // salted, truncated hash of the client IP
+}
+
+var (
+	queue   = make(chan Event, queueSize)
+	dropped atomic.Int64
+)
+
+// Record queues e for the worker. When the queue is full the event is
+// dropped rather than slowing the redirect down.
+func Record(e Event) {
+	select {
+	case queue <- e:
+	default:
+		if dropped.Add(1)%1000 == 1 {
+			log.Println("analytics: queue full, dropping click events")
+		}
+	}
+}
+
+// HashIP turns a client IP into a stable visitor ID that can't be reversed
+// to the address.
+func HashIP(ip string) string {
+	salt := os.Getenv("ANALYTICS_SALT")
+	if salt == "" {
+		salt = os.Getenv("jwt_secret")
+	}
+	sum := sha256.Sum256([]byte(salt + "|" + ip))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Start launches the background writer. Call it once at startup.
+func Start() {
+	go func() {
+		r := database.CreateClient(5)
+		linksDB := database.CreateClient(0)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]Event, 0, batchSize)
+		for {
+			select {
+			case e := <-queue:
+				batch = append(batch, e)
+				if len(batch) < batchSize {
+					continue
+				}
+			case <-ticker.C:
+				if len(batch) == 0 {
+					continue
+				}
+			}
+			if err := flush(r, linksDB, batch); err != nil {
+				log.Println("analytics: failed to write click events:", err)
+			}
+			batch = batch[:0]
+		}
+	}()
+}
+
+func flush(r, linksDB *redis.Client, batch []Event) error {
+	totals := make(map[string]int64)
+	pipe := r.Pipeline()
+	for _, e := range batch {
+		totals[e.ShortID]++
+		at := e.At.UTC()
+		raw, _ := json.Marshal(e)
+
+		pipe.LPush(database.Ctx, eventsKey(e.ShortID), raw)
+		pipe.LTrim(database.Ctx, eventsKey(e.ShortID), 0, maxEvents-1)
+		pipe.HIncrBy(database.Ctx, hourlyKey(e.ShortID), at.Format(hourLayout), 1)
+		pipe.HIncrBy(database.Ctx, dailyKey(e.ShortID), at.Format(dayLayout), 1)
+		pipe.ZIncrBy(database.Ctx, referrersKey(e.ShortID), 1, referrerHost(e.Referrer))
+		pipe.PFAdd(database.Ctx, visitorsKey(e.ShortID), e.Visitor)
+	}
+	for id := range totals {
+		for _, key := range []string{eventsKey(id), hourlyKey(id), dailyKey(id), referrersKey(id), visitorsKey(id)} {
+			pipe.Expire(database.Ctx, key, retention)
+		}
+	}
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return err
+	}
+
+	linkPipe := linksDB.Pipeline()
+	for id, n := range totals {
+		links.AddClicks(linkPipe, id, n)
+	}
+	_, err := linkPipe.Exec(database.Ctx)
+	return err
+}
+
+// referrerHost reduces a Referer header to its host, so top referrers group
+// by site rather than by page.
+func referrerHost(ref string) string {
+	if ref == "" {
+		return "direct"
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	hourLayout = "2006010215"
+	dayLayout  = "20060102"
+)
+
+func eventsKey(id string) string    { return "clicks:" + id + ":events" }
+func hourlyKey(id string) string    { return "clicks:" + id + ":hourly" }
+func dailyKey(id string) string     { return "clicks:" + id + ":daily" }
+func referrersKey(id string) string { return "clicks:" + id + ":referrers" }
+func visitorsKey(id string) string  { return "clicks:" + id + ":visitors" }
+
+// Delete drops every stat recorded for id. Short IDs are reused once a link
+// is deleted or expires, and the next link must not inherit its clicks.
+func Delete(r *redis.Client, id string) error {
+	return r.Del(database.Ctx, eventsKey(id), hourlyKey(id), dailyKey(id), referrersKey(id), visitorsKey(id)).Err()
+}
+
+// Point is one bucket of a click time series.
+type Point struct {
+	Time   time.Time `json:"time"`
+	Clicks int64     `json:"clicks"`
+}
+
+// Referrer is a referring site and the clicks it sent.
+type Referrer struct {
+	Host   string `json:"host"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Stats summarises the recorded clicks for one link.
+type Stats struct {
+	UniqueVisitors int64      `json:"unique_visitors"`
+	Hourly         []Point    `json:"hourly"`
+	Daily          []Point    `json:"daily"`
+	TopReferrers   []Referrer `json:"top_referrers"`
+}
+
+// Load reads the stats for id from DB 5, with hourly buckets for the last
+// hours hours and daily buckets for the last days days, oldest first.
+func Load(r *redis.Client, id string, hours, days, topN int) (*Stats, error) {
+	pipe := r.Pipeline()
+	hourly := pipe.HGetAll(database.Ctx, hourlyKey(id))
+	daily := pipe.HGetAll(database.Ctx, dailyKey(id))
+	visitors := pipe.PFCount(database.Ctx, visitorsKey(id))
+	referrers := pipe.ZRevRangeWithScores(database.Ctx, referrersKey(id), 0, int64(topN-1))
+	if _, err := pipe.Exec(database.Ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	stats := &Stats{
+		UniqueVisitors: visitors.Val(),
+		Hourly:         series(hourly.Val(), now.Truncate(time.Hour), time.Hour, hours, hourLayout),
+		Daily:          series(daily.Val(), now.Truncate(24*time.Hour), 24*time.Hour, days, dayLayout),
+		TopReferrers:   []Referrer{},
+	}
+	for _, z := range referrers.Val() {
+		host, _ := z.Member.(string)
+		stats.TopReferrers = append(stats.TopReferrers, Referrer{Host: host, Clicks: int64(z.Score)})
+	}
+	return stats, nil
+}
+
+// series expands sparse bucket counts into n consecutive points ending at last.
+func series(counts map[string]string, last time.Time, step time.Duration, n int, layout string) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		t := last.Add(-time.Duration(n-1-i) * step)
+		points[i].Time = t
+		if v, ok := counts[t.Format(layout)]; ok {
+			points[i].Clicks, _ = strconv.ParseInt(v, 10, 64)
+		}
+	}
+	return points
+}
@@ -0,0 +1,119 @@
+// Package brands keeps the protected-brand list: domains our users trust
+// (banks, payment providers, the services they log in to) that admins
+// maintain in DB 3. URLs are compared against it to catch lookalike domains
+// such as "paypa1.com" or a Cyrillic spelling of a bank's name.
+package brands
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Key is the DB 3 hash of protected brands, registrable domain to Brand.
+const Key = "brands"
+
+var ErrDomain = errors.New("domain must be a registrable domain such as paypal.com")
+
+// Brand is one protected domain.
+type Brand struct {
+	Domain  string    `json:"domain"`
+	Name    string    `json:"name,omitempty"` // e.g. "PayPal"; defaults to the domain
+	Admin   string    `json:"admin"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// New validates domain, which may be given as a URL or a host, and returns
+// the brand for its registrable domain.
+func New(domain, name, admin string) (*Brand, error) {
+	host := strings.TrimPrefix(canonical.Host(domain), "www.")
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil || registrable != host {
+		return nil, ErrDomain
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		name = registrable
+	}
+	return &Brand{Domain: registrable, Name: name, Admin: admin, AddedAt: time.Now().UTC()}, nil
+}
+
+// Add stores b, replacing any brand with the same domain.
+func Add(r3 *redis.Client, b *Brand) error {
+	val, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if err := r3.HSet(database.Ctx, Key, b.Domain, val).Err(); err != nil {
+		return err
+	}
+	Invalidate()
+	return nil
+}
+
+// Remove deletes the brand for domain, reporting whether there was one.
+func Remove(r3 *redis.Client, domain string) (bool, error) {
+	n, err := r3.HDel(database.Ctx, Key, strings.ToLower(domain)).Result()
+	if err != nil {
+		return false, err
+	}
+	Invalidate()
+	return n > 0, nil
+}
+
+// All reads every brand, sorted by domain, bypassing the cache.
+func All(r3 *redis.Client) ([]*Brand, error) {
+	vals, err := r3.HGetAll(database.Ctx, Key).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Brand, 0, len(vals))
+	for _, val := range vals {
+		var b Brand
+		if err := json.Unmarshal([]byte(val), &b); err == nil {
+			list = append(list, &b)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Domain < list[j].Domain })
+	return list, nil
+}
+
+// The list is read at most every cacheTTL rather than on every submission.
+const cacheTTL = 30 * time.Second
+
+var (
+	cacheMu      sync.Mutex
+	cache        *Set
+	cacheExpires time.Time
+)
+
+// Load returns the current list, from cache when it is fresh.
+func Load() (*Set, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if cache != nil && time.Now().Before(cacheExpires) {
+		return cache, nil
+	}
+	r := database.CreateClient(3)
+	defer r.Close()
+	list, err := All(r)
+	if err != nil {
+		return nil, err
+	}
+	cache, cacheExpires = newSet(list), time.Now().Add(cacheTTL)
+	return cache, nil
+}
+
+// Invalidate makes the next Load read the list again.
+func Invalidate() {
+	cacheMu.Lock()
+	cache = nil
+	cacheMu.Unlock()
+}
@@ -0,0 +1,127 @@
+package brands
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		domain, name   string
+		want, wantName string // want is empty when ErrDomain is expected
+	}{
+		{"paypal.com", "PayPal", "paypal.com", "PayPal"},
+		{"https://www.PayPal.com/signin", "", "paypal.com", "paypal.com"},
+		{"barclays.co.uk", "  Barclays ", "barclays.co.uk", "Barclays"},
+		{"login.paypal.com", "", "", ""},
+		{"co.uk", "", "", ""},
+		{"localhost", "", "", ""},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		b, err := New(tt.domain, tt.name, "admin@example.com")
+		if tt.want == "" {
+			if err != ErrDomain {
+				t.Errorf("New(%q) error = %v, want ErrDomain", tt.domain, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("New(%q) returned error %v", tt.domain, err)
+			continue
+		}
+		if b.Domain != tt.want || b.Name != tt.wantName {
+			t.Errorf("New(%q, %q) = %q %q, want %q %q", tt.domain, tt.name, b.Domain, b.Name, tt.want, tt.wantName)
+		}
+	}
+}
+
+func testSet(t *testing.T, domains ...string) *Set {
+	t.Helper()
+	var list []*Brand
+	for _, d := range domains {
+		b, err := New(d, "", "admin@example.com")
+		if err != nil {
+			t.Fatalf("New(%q): %v", d, err)
+		}
+		list = append(list, b)
+	}
+	return newSet(list)
+}
+
+func TestMatch(t *testing.T) {
+	set := testSet(t, "paypal.com", "barclays.co.uk", "ing.com")
+	tests := []struct {
+		url    string
+		brand  string // empty when no match is expected
+		method string
+	}{
+		{"https://www.paypal.com/signin", "", ""},
+		{"https://paypal.com.evil.net/", "paypal.com", MethodName},
+		{"https://paypal.co/", "paypal.com", MethodName},
+		{"https://paypal.secure-login.com/", "paypal.com", MethodName},
+		{"https://pаypal.com/", "paypal.com", MethodHomograph}, // Cyrillic а
+		{"https://paypa1.com/", "paypal.com", MethodHomograph},
+		{"https://paypql.com/", "paypal.com", MethodKeyboard},
+		{"https://paypall.com/", "paypal.com", MethodTypo},
+		{"https://pyapal.com/", "paypal.com", MethodTypo},
+		{"https://barclays.com/", "barclays.co.uk", MethodName},
+		{"https://barcl4ys.co.uk/", "barclays.co.uk", MethodTypo},
+		{"https://ling.com/", "", ""}, // names under four letters only match exactly
+		{"https://ing.net/", "ing.com", MethodName},
+		{"https://example.com/", "", ""},
+		{"not a url", "", ""},
+	}
+	for _, tt := range tests {
+		m := set.Match(tt.url)
+		switch {
+		case tt.brand == "" && m != nil:
+			t.Errorf("Match(%q) = %+v, want no match", tt.url, *m)
+		case tt.brand != "" && m == nil:
+			t.Errorf("Match(%q) = nil, want %s by %s", tt.url, tt.brand, tt.method)
+		case m != nil && (m.Brand != tt.brand || m.Method != tt.method):
+			t.Errorf("Match(%q) = %s by %s, want %s by %s", tt.url, m.Brand, m.Method, tt.brand, tt.method)
+		}
+	}
+}
+
+func TestMatchEmptySet(t *testing.T) {
+	if m := newSet(nil).Match("https://paypa1.com/"); m != nil {
+		t.Errorf("empty set matched %+v", *m)
+	}
+}
+
+func TestDomains(t *testing.T) {
+	set := testSet(t, "paypal.com", "paypal.co.uk", "ing.com")
+	if got := set.Domains("paypal"); !slices.Equal(got, []string{"paypal.com", "paypal.co.uk"}) {
+		t.Errorf("Domains(paypal) = %v", got)
+	}
+	if got := set.Domains("chase"); got != nil {
+		t.Errorf("Domains(chase) = %v, want none", got)
+	}
+	var none *Set
+	if got := none.Domains("paypal"); got != nil {
+		t.Errorf("nil set Domains(paypal) = %v, want none", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		plain    int
+		weighted float64
+	}{
+		{"paypal", "paypal", 0, 0},
+		{"paypal", "paypql", 1, 0.5},
+		{"paypal", "paypml", 1, 1},
+		{"paypal", "pyapal", 1, 1},
+		{"paypal", "paypall", 1, 1},
+		{"paypal", "pay", 3, 3},
+	}
+	for _, tt := range tests {
+		plain, weighted := distance(tt.a, tt.b)
+		if plain != tt.plain || weighted != tt.weighted {
+			t.Errorf("distance(%q, %q) = %d, %v, want %d, %v", tt.a, tt.b, plain, weighted, tt.plain, tt.weighted)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package brands
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Ways a domain can imitate a brand.
+const (
+	MethodHomograph = "homograph" // looks the same: "pаypal" with a Cyrillic а, "paypa1"
+	MethodKeyboard  = "keyboard"  // a slip onto a neighbouring key: "paypql"
+	MethodTypo      = "typo"      // a letter added, dropped, changed or swapped: "paypall"
+	MethodName      = "name"      // the brand's name on another domain: "paypal.co", "paypal.evil.com"
+)
+
+// Match is a URL whose host imitates a protected brand.
+type Match struct {
+	Brand  string `json:"brand"`  // the brand's domain
+	Name   string `json:"name"`   // the brand's name
+	Label  string `json:"label"`  // the part of the host that imitates it
+	Method string `json:"method"` // homograph, keyboard, typo or name
+}
+
+// Set is the protected list prepared for matching.
+type Set struct {
+	brands []setBrand
+}
+
+type setBrand struct {
+	*Brand
+	label    string // the domain without its public suffix, e.g. "paypal"
+	skeleton string
+}
+
+func newSet(list []*Brand) *Set {
+	s := &Set{}
+	for _, b := range list {
+		label := nameLabel(b.Domain)
+		s.brands = append(s.brands, setBrand{Brand: b, label: label, skeleton: skeleton(label)})
+	}
+	return s
+}
+
+// nameLabel returns domain with its public suffix removed: "paypal" for
+// "paypal.co.uk".
+func nameLabel(domain string) string {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return strings.TrimSuffix(strings.TrimSuffix(domain, suffix), ".")
+}
+
+// Match compares the host of url with every protected brand and returns the
+// closest imitation, or nil. Hosts on a brand's own domain never match.
+// Every label in front of the public suffix is compared, so the imitation
+// can sit in a subdomain too.
+func (s *Set) Match(url string) *Match {
+	host := canonical.Host(url)
+	if host == "" || len(s.brands) == 0 {
+		return nil
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return nil
+	}
+	for _, b := range s.brands {
+		if registrable == b.Domain {
+			return nil
+		}
+	}
+	var best *Match
+	bestRank := len(methodRank)
+	for _, label := range strings.Split(nameLabel(host), ".") {
+		if label == "" {
+			continue
+		}
+		unicodeLabel, err := idna.ToUnicode(label)
+		if err != nil {
+			unicodeLabel = label
+		}
+		labelSkeleton := skeleton(unicodeLabel)
+		for _, b := range s.brands {
+			method := compare(label, labelSkeleton, b)
+			if method == "" || methodRank[method] >= bestRank {
+				continue
+			}
+			best = &Match{Brand: b.Domain, Name: b.Name, Label: unicodeLabel, Method: method}
+			bestRank = methodRank[method]
+		}
+	}
+	return best
+}
+
+// Domains returns the domains of the protected brands named label, such as
+// "paypal.com" for "paypal". A nil set has none.
+func (s *Set) Domains(label string) []string {
+	if s == nil {
+		return nil
+	}
+	var domains []string
+	for _, b := range s.brands {
+		if b.label == label {
+			domains = append(domains, b.Domain)
+		}
+	}
+	return domains
+}
+
+// methodRank orders the methods from most to least telling.
+var methodRank = map[string]int{
+	MethodName:      0,
+	MethodHomograph: 1,
+	MethodKeyboard:  2,
+	MethodTypo:      3,
+}
+
+func compare(label, labelSkeleton string, b setBrand) string {
+	switch {
+	case label == b.label:
+		return MethodName
+	case labelSkeleton == b.skeleton:
+		return MethodHomograph
+	}
+	// Short names are a typo away from too many real words to compare
+	n := len([]rune(b.label))
+	if n < 4 {
+		return ""
+	}
+	plain, weighted := distance(labelSkeleton, b.skeleton)
+	switch {
+	case weighted < float64(plain) && weighted <= 1:
+		return MethodKeyboard
+	case plain == 1, plain == 2 && n >= 8:
+		return MethodTypo
+	}
+	return ""
+}
+
+// skeleton reduces s to a form in which confusable spellings coincide, after
+// Unicode TR39: s is decomposed, accents are dropped, and every character is
+// replaced by the prototype it is confusable with, then lowercased.
+func skeleton(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if p, ok := confusables[r]; ok {
+			b.WriteString(p)
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sequences.Replace(b.String())
+}
+
+// confusables maps characters to the ASCII letters they are mistaken for,
+// drawn from the TR39 confusables table: digits and symbols, and the
+// Cyrillic, Greek and other letters seen in lookalike domains.
+var confusables = map[rune]string{
+	'0': "o", '1': "l", '3': "e", '5': "s", '8': "b", '|': "l", '!': "l",
+	'ı': "l", 'ɩ': "l", 'ӏ': "l", 'ι': "l", 'і': "l", 'ǀ': "l",
+	'а': "a", 'α': "a", 'ɑ': "a",
+	'Ь': "b", 'ƅ': "b",
+	'с': "c", 'ϲ': "c", 'ⅽ': "c",
+	'ԁ': "d", 'ⅾ': "d",
+	'е': "e", 'ҽ': "e", 'ε': "e",
+	'ɡ': "g", 'ց': "g",
+	'һ': "h", 'հ': "h",
+	'ј': "j", 'ϳ': "j",
+	'κ': "k", 'к': "k",
+	'м': "m", 'ⅿ': "m",
+	'ո': "n", 'η': "n", 'п': "n",
+	'о': "o", 'ο': "o", 'օ': "o", 'σ': "o",
+	'р': "p", 'ρ': "p",
+	'ԛ': "q",
+	'г': "r",
+	'ѕ': "s",
+	'т': "t", 'τ': "t",
+	'υ': "u", 'ս': "u",
+	'ν': "v", 'ѵ': "v",
+	'ԝ': "w", 'ѡ': "w",
+	'х': "x", 'χ': "x",
+	'у': "y", 'γ': "y", 'ү': "y",
+	'ᴢ': "z",
+}
+
+// sequences are letter pairs that read as one letter.
+var sequences = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// keyboard holds the rows of a QWERTY keyboard, for telling slips onto a
+// neighbouring key from other typos.
+var keyboard = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+var keyPos = func() map[rune][2]int {
+	pos := map[rune][2]int{}
+	for row, keys := range keyboard {
+		for col, k := range keys {
+			pos[k] = [2]int{row, col}
+		}
+	}
+	return pos
+}()
+
+// adjacent reports whether a and b are neighbouring keys, in the same row or
+// the rows above and below it.
+func adjacent(a, b rune) bool {
+	pa, ok1 := keyPos[a]
+	pb, ok2 := keyPos[b]
+	if !ok1 || !ok2 || a == b {
+		return false
+	}
+	dr, dc := pa[0]-pb[0], pa[1]-pb[1]
+	if dr == 0 {
+		return dc == 1 || dc == -1
+	}
+	// Rows are staggered half a key to the right going down
+	if dr == -1 {
+		return dc == 0 || dc == 1
+	}
+	if dr == 1 {
+		return dc == 0 || dc == -1
+	}
+	return false
+}
+
+// distance returns the optimal string alignment distance between a and b
+// (insertions, deletions, substitutions and swaps of neighbours each cost
+// one) and the same distance with substitutions of adjacent keys costing a
+// half.
+func distance(a, b string) (int, float64) {
+	ra, rb := []rune(a), []rune(b)
+	plain := osa(ra, rb, func(x, y rune) float64 { return 1 })
+	weighted := osa(ra, rb, func(x, y rune) float64 {
+		if adjacent(x, y) {
+			return 0.5
+		}
+		return 1
+	})
+	return int(plain), weighted
+}
+
+func osa(a, b []rune, subCost func(x, y rune) float64) float64 {
+	d := make([][]float64, len(a)+1)
+	for i := range d {
+		d[i] = make([]float64, len(b)+1)
+		d[i][0] = float64(i)
+	}
+	for j := range d[0] {
+		d[0][j] = float64(j)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 0.0
+			if a[i-1] != b[j-1] {
+				cost = subCost(a[i-1], b[j-1])
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,164 @@
+// Package canonical reduces URLs to one spelling, so that records keyed by
+// URL (scam reports, verified scams, the link destination index) don't split
+// across "http://Evil.com/", "https://evil.com" and "evil.com/?utm_source=x".
+package canonical
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrNoHost = errors.New("URL has no host")
+
+// trackingParams are query parameters that only identify the campaign or
+// click a URL was shared from, never the page itself.
+var trackingParams = map[string]bool{
+	"fbclid":      true,
+	"gclid":       true,
+	"dclid":       true,
+	"gbraid":      true,
+	"wbraid":      true,
+	"msclkid":     true,
+	"yclid":       true,
+	"twclid":      true,
+	"igshid":      true,
+	"mc_cid":      true,
+	"mc_eid":      true,
+	"_ga":         true,
+	"_gl":         true,
+	"_hsenc":      true,
+	"_hsmi":       true,
+	"mkt_tok":     true,
+	"oly_anon_id": true,
+	"oly_enc_id":  true,
+	"vero_id":     true,
+}
+
+func isTracking(param string) bool {
+	param = strings.ToLower(param)
+	return strings.HasPrefix(param, "utm_") || trackingParams[param]
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URL returns the canonical form of raw: scheme and host lowercased, the host
+// in IDNA (punycode) form, default ports, user info, fragments and tracking
+// parameters dropped, the remaining query sorted, and dot segments and
+// repeated slashes removed from the path. A missing scheme means http.
+func URL(raw string) (string, error) {
+	u, err := parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Key is the scheme-less form of URL without a trailing slash, e.g.
+// "evil.com/login?id=1". Records that should match a URL however it was
+// typed are stored under its Key. URLs without a host have no key and give
+// "". Other strings that don't parse as URLs are only trimmed and lowercased.
+//
+// Without its scheme a key reads back as http, so a port that is the default
+// for either scheme is dropped too; otherwise "https://evil.com:80/x" would
+// key as "evil.com:80/x" and that as "evil.com/x". Key(Key(x)) == Key(x).
+func Key(raw string) string {
+	u, err := parse(raw)
+	if err == ErrNoHost {
+		return ""
+	}
+	if err != nil {
+		return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(raw), "/"))
+	}
+	host := u.Host
+	if port := u.Port(); port == defaultPorts["http"] || port == defaultPorts["https"] {
+		host = u.Hostname()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+	}
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// Host returns the canonical host of raw, without a port.
+func Host(raw string) string {
+	u, err := parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func parse(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return nil, ErrNoHost
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+	u.User = nil
+	u.Fragment, u.RawFragment = "", ""
+	u.Opaque = ""
+
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	u.Path, u.RawPath = cleaned, ""
+
+	query := u.Query()
+	for param := range query {
+		if isTracking(param) {
+			delete(query, param)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u, nil
+}
+
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	// Hosts idna rejects (underscores and the like) are still worth matching
+	return host
+}
@@ -0,0 +1,227 @@
+// Package codegen generates short codes for new links.
+//
+// A Generator only proposes codes; callers claim them atomically in Redis and
+// ask for another candidate (with a higher attempt number) on collision.
+package codegen
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+)
+
+// DefaultAlphabet is base62 without the characters people confuse when
+// reading or typing a code: 0/O/o, 1/l/I.
+const DefaultAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz"
+
+const (
+	DefaultLength      = 7
+	DefaultMaxAttempts = 5
+)
+
+// Generator proposes a short code for url. attempt starts at 0 and grows each
+// time the previous candidate turned out to be taken.
+type Generator interface {
+	Next(url string, attempt int) (string, error)
+}
+
+// Config selects and tunes a generator.
+type Config struct {
+	Strategy    string // "random", "counter" or "hash"
+	Length      int
+	Alphabet    string
+	MaxAttempts int
+}
+
+// ConfigFromEnv reads CODEGEN_STRATEGY, CODEGEN_LENGTH, CODEGEN_ALPHABET and
+// CODEGEN_MAX_ATTEMPTS, falling back to random codes of DefaultLength.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Strategy:    os.Getenv("CODEGEN_STRATEGY"),
+		Alphabet:    os.Getenv("CODEGEN_ALPHABET"),
+		Length:      DefaultLength,
+		MaxAttempts: DefaultMaxAttempts,
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = "random"
+	}
+	if cfg.Alphabet == "" {
+		cfg.Alphabet = DefaultAlphabet
+	}
+	if n, err := strconv.Atoi(os.Getenv("CODEGEN_LENGTH")); err == nil && n > 0 {
+		cfg.Length = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("CODEGEN_MAX_ATTEMPTS")); err == nil && n > 0 {
+		cfg.MaxAttempts = n
+	}
+	return cfg
+}
+
+// New builds the generator described by cfg.
+func New(cfg Config) (Generator, error) {
+	if cfg.Length <= 0 {
+		return nil, errors.New("codegen: length must be positive")
+	}
+	alphabet, err := checkAlphabet(cfg.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Strategy {
+	case "random":
+		return &randomGen{alphabet: alphabet, length: cfg.Length}, nil
+	case "counter":
+		return &counterGen{alphabet: alphabet, length: cfg.Length, next: redisCounter}, nil
+	case "hash":
+		return &hashGen{alphabet: alphabet, length: cfg.Length}, nil
+	}
+	return nil, fmt.Errorf("codegen: unknown strategy %q", cfg.Strategy)
+}
+
+var (
+	defaultOnce sync.Once
+	defaultGen  Generator
+	defaultCfg  Config
+)
+
+// Default returns the generator configured from the environment, along with
+// its configuration. An invalid configuration falls back to the defaults.
+func Default() (Generator, Config) {
+	defaultOnce.Do(func() {
+		defaultCfg = ConfigFromEnv()
+		gen, err := New(defaultCfg)
+		if err != nil {
+			log.Println(err, "- using random codes")
+			defaultCfg = Config{Strategy: "random", Length: DefaultLength, Alphabet: DefaultAlphabet, MaxAttempts: defaultCfg.MaxAttempts}
+			gen, _ = New(defaultCfg)
+		}
+		defaultGen = gen
+	})
+	return defaultGen, defaultCfg
+}
+
+func checkAlphabet(alphabet string) ([]rune, error) {
+	runes := []rune(alphabet)
+	if len(runes) < 2 {
+		return nil, errors.New("codegen: alphabet needs at least two characters")
+	}
+	seen := make(map[rune]bool, len(runes))
+	for _, r := range runes {
+		if seen[r] {
+			return nil, fmt.Errorf("codegen: alphabet repeats %q", r)
+		}
+		if strings.ContainsRune("/?#%&:@ ", r) {
+			return nil, fmt.Errorf("codegen: %q is not URL-safe", r)
+		}
+		seen[r] = true
+	}
+	return runes, nil
+}
+
+// randomGen draws every character uniformly from the alphabet.
+type randomGen struct {
+	alphabet []rune
+	length   int
+}
+
+func (g *randomGen) Next(string, int) (string, error) {
+	max := big.NewInt(int64(len(g.alphabet)))
+	code := make([]rune, g.length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = g.alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// counterGen encodes a global sequence number, left-padded to length, so
+// codes stay as short as possible and never repeat.
+type counterGen struct {
+	alphabet []rune
+	length   int
+	next     func() (int64, error)
+}
+
+func (g *counterGen) Next(string, int) (string, error) {
+	n, err := g.next()
+	if err != nil {
+		return "", err
+	}
+	return encode(big.NewInt(n), g.alphabet, g.length), nil
+}
+
+// The counter lives in DB 3, out of the short-code keyspace in DB 0 where it
+// was first kept.
+const (
+	counterKey      = "codegen:counter"
+	legacyCounterDB = 0
+	counterDB       = 3
+)
+
+var moveCounter sync.Once
+
+func redisCounter() (int64, error) {
+	r := database.CreateClient(counterDB)
+	defer r.Close()
+	moveCounter.Do(func() {
+		old := database.CreateClient(legacyCounterDB)
+		defer old.Close()
+		if n, err := old.Get(database.Ctx, counterKey).Int64(); err == nil {
+			// Carry on from the old count so no code is handed out twice
+			if err := r.SetNX(database.Ctx, counterKey, n, 0).Err(); err == nil {
+				old.Del(database.Ctx, counterKey)
+			}
+		}
+	})
+	return r.Incr(database.Ctx, counterKey).Result()
+}
+
+// hashGen derives the code from the URL, so the same URL gets the same code
+// unless it is taken, in which case the attempt number is mixed in.
+type hashGen struct {
+	alphabet []rune
+	length   int
+}
+
+func (g *hashGen) Next(url string, attempt int) (string, error) {
+	input := url
+	if attempt > 0 {
+		input = fmt.Sprintf("%s#%d", url, attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+	code := encode(new(big.Int).SetBytes(sum[:]), g.alphabet, 0)
+	if len([]rune(code)) > g.length {
+		code = string([]rune(code)[:g.length])
+	}
+	return code, nil
+}
+
+// encode writes n in the given alphabet, left-padded to at least width.
+func encode(n *big.Int, alphabet []rune, width int) string {
+	base := big.NewInt(int64(len(alphabet)))
+	var out []rune
+	n = new(big.Int).Set(n)
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, alphabet[mod.Int64()])
+	}
+	for len(out) < width {
+		out = append(out, alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,163 @@
+package codegen
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestCheckAlphabet(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		ok       bool
+	}{
+		{DefaultAlphabet, true},
+		{"ab", true},
+		{"αβγ", true},
+		{"", false},
+		{"a", false},
+		{"abca", false},
+		{"ab/", false},
+		{"ab?", false},
+		{"ab#", false},
+		{"ab%", false},
+		{"ab c", false},
+	}
+	for _, tt := range tests {
+		runes, err := checkAlphabet(tt.alphabet)
+		if tt.ok && (err != nil || string(runes) != tt.alphabet) {
+			t.Errorf("checkAlphabet(%q) = %q, %v, want it accepted", tt.alphabet, string(runes), err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("checkAlphabet(%q) accepted, want an error", tt.alphabet)
+		}
+	}
+}
+
+func TestDefaultAlphabetHasNoLookalikes(t *testing.T) {
+	for _, r := range "0Oo1lI" {
+		if strings.ContainsRune(DefaultAlphabet, r) {
+			t.Errorf("DefaultAlphabet contains %q", r)
+		}
+	}
+	if _, err := checkAlphabet(DefaultAlphabet); err != nil {
+		t.Errorf("DefaultAlphabet rejected: %v", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	binary := []rune("01")
+	base62 := []rune("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	tests := []struct {
+		n        int64
+		alphabet []rune
+		width    int
+		want     string
+	}{
+		{0, binary, 0, ""},
+		{0, binary, 3, "000"},
+		{5, binary, 0, "101"},
+		{5, binary, 6, "000101"},
+		{5, binary, 2, "101"}, // width is a minimum
+		{61, base62, 0, "z"},
+		{62, base62, 0, "10"},
+		{3843, base62, 0, "zz"},
+		{1, []rune(DefaultAlphabet), 7, "2222223"},
+	}
+	for _, tt := range tests {
+		if got := encode(big.NewInt(tt.n), tt.alphabet, tt.width); got != tt.want {
+			t.Errorf("encode(%d, %q, %d) = %q, want %q", tt.n, string(tt.alphabet), tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		ok  bool
+	}{
+		{Config{Strategy: "random", Length: 7, Alphabet: DefaultAlphabet}, true},
+		{Config{Strategy: "counter", Length: 7, Alphabet: DefaultAlphabet}, true},
+		{Config{Strategy: "hash", Length: 7, Alphabet: DefaultAlphabet}, true},
+		{Config{Strategy: "sequential", Length: 7, Alphabet: DefaultAlphabet}, false},
+		{Config{Strategy: "random", Length: 0, Alphabet: DefaultAlphabet}, false},
+		{Config{Strategy: "random", Length: 7, Alphabet: "aa"}, false},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.cfg)
+		if (err == nil) != tt.ok {
+			t.Errorf("New(%+v) error = %v, want ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("CODEGEN_STRATEGY", "")
+	t.Setenv("CODEGEN_ALPHABET", "")
+	t.Setenv("CODEGEN_LENGTH", "nope")
+	t.Setenv("CODEGEN_MAX_ATTEMPTS", "-1")
+	want := Config{Strategy: "random", Alphabet: DefaultAlphabet, Length: DefaultLength, MaxAttempts: DefaultMaxAttempts}
+	if got := ConfigFromEnv(); got != want {
+		t.Errorf("ConfigFromEnv() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("CODEGEN_STRATEGY", "hash")
+	t.Setenv("CODEGEN_ALPHABET", "abc")
+	t.Setenv("CODEGEN_LENGTH", "9")
+	t.Setenv("CODEGEN_MAX_ATTEMPTS", "3")
+	want = Config{Strategy: "hash", Alphabet: "abc", Length: 9, MaxAttempts: 3}
+	if got := ConfigFromEnv(); got != want {
+		t.Errorf("ConfigFromEnv() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHashDeterministic(t *testing.T) {
+	gen, err := New(Config{Strategy: "hash", Length: 7, Alphabet: DefaultAlphabet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]string{}
+	for _, url := range []string{"https://example.com/", "https://example.com/a", "https://example.org/"} {
+		first, _ := gen.Next(url, 0)
+		again, _ := gen.Next(url, 0)
+		if first != again {
+			t.Errorf("Next(%q, 0) gave %q then %q", url, first, again)
+		}
+		if len([]rune(first)) != 7 || strings.Trim(first, DefaultAlphabet) != "" {
+			t.Errorf("Next(%q, 0) = %q, want 7 characters of the alphabet", url, first)
+		}
+		if other, ok := seen[first]; ok {
+			t.Errorf("%q and %q share code %q", url, other, first)
+		}
+		seen[first] = url
+		if retry, _ := gen.Next(url, 1); retry == first {
+			t.Errorf("Next(%q, 1) = %q, the same as attempt 0", url, retry)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	n := int64(0)
+	gen := &counterGen{alphabet: []rune("01"), length: 4, next: func() (int64, error) {
+		n++
+		return n, nil
+	}}
+	for _, want := range []string{"0001", "0010", "0011", "0100"} {
+		if got, err := gen.Next("ignored", 0); err != nil || got != want {
+			t.Errorf("Next() = %q, %v, want %q", got, err, want)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	gen, err := New(Config{Strategy: "random", Length: 12, Alphabet: "xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		code, err := gen.Next("", i)
+		if err != nil || len(code) != 12 || strings.Trim(code, "xyz") != "" {
+			t.Fatalf("Next() = %q, %v, want 12 characters of xyz", code, err)
+		}
+	}
+}
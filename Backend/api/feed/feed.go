@@ -0,0 +1,342 @@
+// Package feed renders the admin-verified scams as blocklists in the formats
+// firewalls, DNS resolvers and ad blockers consume.
+//
+// Host-level formats (hosts, domains, rpz) can only block whole hosts, so
+// they list domain entries and the hosts of verified URLs that cover a whole
+// site (no path or query); blocking a host for one bad page on it would take
+// down everything else it serves. The other formats carry every entry.
+// Hosts with characters a format has no way to write are escaped where it
+// allows (rpz) and left out where it doesn't (hosts, domains, adblock).
+package feed
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/google/uuid"
+)
+
+// Formats served.
+const (
+	Hosts   = "hosts"
+	Domains = "domains"
+	CSV     = "csv"
+	Adblock = "adblock"
+	RPZ     = "rpz"
+	STIX    = "stix"
+)
+
+var Formats = []string{Hosts, Domains, CSV, Adblock, RPZ, STIX}
+
+var ErrFormat = errors.New("format must be one of hosts, domains, csv, adblock, rpz or stix")
+
+var contentTypes = map[string]string{
+	Hosts:   "text/plain; charset=utf-8",
+	Domains: "text/plain; charset=utf-8",
+	CSV:     "text/csv; charset=utf-8",
+	Adblock: "text/plain; charset=utf-8",
+	RPZ:     "text/dns; charset=utf-8",
+	STIX:    "application/stix+json;version=2.1",
+}
+
+// ContentType returns the media type a format is served as.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Render writes entries in format. Updated is when the list last changed;
+// it dates the feed and serves as the RPZ serial.
+func Render(format string, entries []*scamrules.Entry, updated time.Time) ([]byte, error) {
+	// Stable output, so unchanged lists render byte for byte the same
+	sorted := append([]*scamrules.Entry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Scope != sorted[j].Scope {
+			return sorted[i].Scope < sorted[j].Scope
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	switch format {
+	case Hosts:
+		return hosts(sorted, updated), nil
+	case Domains:
+		return domains(sorted, updated), nil
+	case CSV:
+		return csvFeed(sorted)
+	case Adblock:
+		return adblock(sorted, updated), nil
+	case RPZ:
+		return rpz(sorted, updated), nil
+	case STIX:
+		return stix(sorted, updated)
+	}
+	return nil, ErrFormat
+}
+
+// ETag tags a feed rendering. It changes only when the format, the verified
+// list version or the scoped entries do.
+func ETag(format string, version int64, entriesAt time.Time) string {
+	return fmt.Sprintf(`"%s-%d-%d"`, format, version, entriesAt.UnixNano())
+}
+
+// NotModified applies the If-None-Match and If-Modified-Since request
+// headers to a feed tagged etag and last changed at updated, If-None-Match
+// taking precedence as RFC 9110 requires.
+func NotModified(ifNoneMatch, ifModifiedSince, etag string, updated time.Time) bool {
+	if ifNoneMatch != "" {
+		for _, match := range strings.Split(ifNoneMatch, ",") {
+			match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+			if match == etag || match == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil || updated.IsZero() {
+		return false
+	}
+	return !updated.Truncate(time.Second).After(since)
+}
+
+// blockedHosts returns the hosts a host-level feed can block.
+func blockedHosts(entries []*scamrules.Entry) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(host string) {
+		if host != "" && !seen[host] {
+			seen[host] = true
+			out = append(out, host)
+		}
+	}
+	for _, e := range entries {
+		switch e.Scope {
+		case scamrules.ScopeDomain:
+			add(e.Value)
+		case scamrules.ScopeURL:
+			host := canonical.Host(e.Value)
+			if canonical.Key(e.Value) == host {
+				add(host)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func header(buf *bytes.Buffer, comment string, updated time.Time) {
+	fmt.Fprintf(buf, "%s Scam blocklist\n", comment)
+	if !updated.IsZero() {
+		fmt.Fprintf(buf, "%s Last modified: %s\n", comment, updated.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(buf, "%s\n", comment)
+}
+
+// plainHost reports whether host is made only of the characters hosts files
+// and domain lists can carry; they have no escaping, so anything else is
+// left out of them.
+func plainHost(host string) bool {
+	for _, r := range host {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// zoneName escapes host for a zone file as RFC 1035 section 5.1 allows:
+// printable specials behind a backslash, anything else as \DDD.
+func zoneName(host string) string {
+	var b strings.Builder
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.':
+			b.WriteByte(c)
+		case c > ' ' && c < 0x7f:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "\\%03d", c)
+		}
+	}
+	return b.String()
+}
+
+func hosts(entries []*scamrules.Entry, updated time.Time) []byte {
+	var buf bytes.Buffer
+	header(&buf, "#", updated)
+	for _, host := range blockedHosts(entries) {
+		if plainHost(host) {
+			fmt.Fprintf(&buf, "0.0.0.0 %s\n", host)
+		}
+	}
+	return buf.Bytes()
+}
+
+func domains(entries []*scamrules.Entry, updated time.Time) []byte {
+	var buf bytes.Buffer
+	header(&buf, "#", updated)
+	for _, host := range blockedHosts(entries) {
+		if plainHost(host) {
+			fmt.Fprintln(&buf, host)
+		}
+	}
+	return buf.Bytes()
+}
+
+func csvFeed(entries []*scamrules.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"scope", "value", "kind", "host", "admin", "reason", "added_at"})
+	for _, e := range entries {
+		host := ""
+		if e.Scope != scamrules.ScopePattern {
+			host = canonical.Host(e.Value)
+		}
+		added := ""
+		if !e.CreatedAt.IsZero() {
+			added = e.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		w.Write([]string{e.Scope, e.Value, e.Kind, host, e.Admin, e.Reason, added})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func adblock(entries []*scamrules.Entry, updated time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[Adblock Plus 2.0]\n! Title: Scam blocklist\n")
+	if !updated.IsZero() {
+		fmt.Fprintf(&buf, "! Last modified: %s\n", updated.UTC().Format(time.RFC3339))
+	}
+	for _, e := range entries {
+		var rule string
+		switch e.Scope {
+		case scamrules.ScopeDomain:
+			rule = "||" + e.Value + "^"
+		case scamrules.ScopeURL:
+			key := canonical.Key(e.Value)
+			if key == canonical.Host(e.Value) {
+				rule = "||" + key + "^"
+			} else {
+				rule = "||" + key + "|"
+			}
+		case scamrules.ScopePrefix:
+			rule = "||" + e.Value
+		case scamrules.ScopePattern:
+			// "||" already covers subdomains; regexes aren't portable
+			// across blockers and are left out
+			if e.Kind == scamrules.KindGlob {
+				rule = "||" + strings.TrimPrefix(e.Value, "*.")
+			}
+		}
+		// "$" starts a rule's options and can't be escaped
+		if rule != "" && !strings.Contains(rule, "$") {
+			buf.WriteString(rule + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+func rpz(entries []*scamrules.Entry, updated time.Time) []byte {
+	var buf bytes.Buffer
+	serial := updated.Unix()
+	if serial <= 0 {
+		serial = 1
+	}
+	buf.WriteString("$TTL 300\n")
+	fmt.Fprintf(&buf, "@ IN SOA localhost. hostmaster.localhost. %d 3600 600 86400 300\n", serial)
+	buf.WriteString("@ IN NS localhost.\n")
+	for _, host := range blockedHosts(entries) {
+		// CNAME to the root answers NXDOMAIN, for the host and below it
+		name := zoneName(host)
+		fmt.Fprintf(&buf, "%s CNAME .\n*.%s CNAME .\n", name, name)
+	}
+	return buf.Bytes()
+}
+
+// stixNamespace seeds the deterministic indicator IDs, so an entry keeps
+// its ID across feed fetches.
+var stixNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:url-shortner:scam-feed"))
+
+type stixIndicator struct {
+	Type           string   `json:"type"`
+	SpecVersion    string   `json:"spec_version"`
+	ID             string   `json:"id"`
+	Created        string   `json:"created"`
+	Modified       string   `json:"modified"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	IndicatorTypes []string `json:"indicator_types"`
+	Pattern        string   `json:"pattern"`
+	PatternType    string   `json:"pattern_type"`
+	ValidFrom      string   `json:"valid_from"`
+}
+
+func stixPattern(e *scamrules.Entry) string {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	switch e.Scope {
+	case scamrules.ScopeDomain:
+		return "[domain-name:value = " + quote(e.Value) + "]"
+	case scamrules.ScopeURL:
+		return "[url:value = " + quote(e.Value) + "]"
+	case scamrules.ScopePrefix:
+		return "[url:value LIKE " + quote("%"+e.Value+"%") + "]"
+	case scamrules.ScopePattern:
+		if e.Kind == scamrules.KindRegex {
+			return "[url:value MATCHES " + quote(e.Value) + "]"
+		}
+		return "[url:value LIKE " + quote("%"+strings.ReplaceAll(e.Value, "*", "%")+"%") + "]"
+	}
+	return ""
+}
+
+func stix(entries []*scamrules.Entry, updated time.Time) ([]byte, error) {
+	const stamp = "2006-01-02T15:04:05.000Z"
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	objects := make([]stixIndicator, 0, len(entries))
+	for _, e := range entries {
+		created := e.CreatedAt
+		if created.IsZero() {
+			created = updated
+		}
+		objects = append(objects, stixIndicator{
+			Type:           "indicator",
+			SpecVersion:    "2.1",
+			ID:             "indicator--" + uuid.NewSHA1(stixNamespace, []byte(e.ID)).String(),
+			Created:        created.UTC().Format(stamp),
+			Modified:       created.UTC().Format(stamp),
+			Name:           "Scam " + e.Scope + ": " + e.Value,
+			Description:    e.Reason,
+			IndicatorTypes: []string{"malicious-activity"},
+			Pattern:        stixPattern(e),
+			PatternType:    "stix",
+			ValidFrom:      created.UTC().Format(stamp),
+		})
+	}
+	bundle := struct {
+		Type    string          `json:"type"`
+		ID      string          `json:"id"`
+		Objects []stixIndicator `json:"objects"`
+	}{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.NewSHA1(stixNamespace, []byte(updated.UTC().Format(stamp))).String(),
+		Objects: objects,
+	}
+	return json.MarshalIndent(bundle, "", "  ")
+}
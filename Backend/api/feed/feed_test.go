@@ -0,0 +1,247 @@
+package feed
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+)
+
+var (
+	updated = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	created = time.Date(2024, 4, 30, 8, 0, 0, 0, time.UTC)
+)
+
+func testEntries(t *testing.T) []*scamrules.Entry {
+	t.Helper()
+	specs := []struct{ scope, value, kind, reason string }{
+		{scamrules.ScopeDomain, "evil.com", "", "phishing kit"},
+		{scamrules.ScopeURL, "https://whole.example.net/", "", ""},
+		{scamrules.ScopeURL, "https://shared.example.org/login?id=1", "", `says "urgent", asks for card`},
+		{scamrules.ScopePrefix, "https://host.example/phish/", "", ""},
+		{scamrules.ScopePattern, "*.bad.example/pay*", scamrules.KindGlob, ""},
+		{scamrules.ScopePattern, `x\.example/'[0-9]+`, scamrules.KindRegex, ""},
+		{scamrules.ScopeURL, "http://a;b$c.example/", "", ""},
+	}
+	var entries []*scamrules.Entry
+	for _, s := range specs {
+		e, err := scamrules.New(s.scope, s.value, s.kind, "admin@example.com", s.reason)
+		if err != nil {
+			t.Fatalf("New(%q, %q): %v", s.scope, s.value, err)
+		}
+		e.CreatedAt = created
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestRenderText(t *testing.T) {
+	entries := testEntries(t)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{Hosts, "# Scam blocklist\n" +
+			"# Last modified: 2024-05-01T12:00:00Z\n" +
+			"#\n" +
+			"0.0.0.0 evil.com\n" +
+			"0.0.0.0 whole.example.net\n"},
+		{Domains, "# Scam blocklist\n" +
+			"# Last modified: 2024-05-01T12:00:00Z\n" +
+			"#\n" +
+			"evil.com\n" +
+			"whole.example.net\n"},
+		{Adblock, "[Adblock Plus 2.0]\n" +
+			"! Title: Scam blocklist\n" +
+			"! Last modified: 2024-05-01T12:00:00Z\n" +
+			"||evil.com^\n" +
+			"||bad.example/pay*\n" +
+			"||host.example/phish\n" +
+			"||shared.example.org/login?id=1|\n" +
+			"||whole.example.net^\n"},
+		{RPZ, "$TTL 300\n" +
+			"@ IN SOA localhost. hostmaster.localhost. 1714564800 3600 600 86400 300\n" +
+			"@ IN NS localhost.\n" +
+			`a\;b\$c.example CNAME .` + "\n" +
+			`*.a\;b\$c.example CNAME .` + "\n" +
+			"evil.com CNAME .\n" +
+			"*.evil.com CNAME .\n" +
+			"whole.example.net CNAME .\n" +
+			"*.whole.example.net CNAME .\n"},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.format, entries, updated)
+		if err != nil {
+			t.Errorf("Render(%s) returned error %v", tt.format, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Render(%s) =\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	body, err := Render(CSV, testEntries(t), updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := ContentType(CSV); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("ContentType(csv) = %q", ct)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	if err != nil {
+		t.Fatalf("feed doesn't parse back: %v\n%s", err, body)
+	}
+	const added = "2024-04-30T08:00:00Z"
+	want := [][]string{
+		{"scope", "value", "kind", "host", "admin", "reason", "added_at"},
+		{"domain", "evil.com", "", "evil.com", "admin@example.com", "phishing kit", added},
+		{"pattern", "*.bad.example/pay*", "glob", "", "admin@example.com", "", added},
+		{"pattern", `x\.example/'[0-9]+`, "regex", "", "admin@example.com", "", added},
+		{"prefix", "host.example/phish", "", "host.example", "admin@example.com", "", added},
+		{"url", "http://a;b$c.example/", "", "a;b$c.example", "admin@example.com", "", added},
+		{"url", "https://shared.example.org/login?id=1", "", "shared.example.org", "admin@example.com", `says "urgent", asks for card`, added},
+		{"url", "https://whole.example.net/", "", "whole.example.net", "admin@example.com", "", added},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d:\n%s", len(rows), len(want), body)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+	if !strings.Contains(string(body), `"says ""urgent"", asks for card"`) {
+		t.Errorf("reason not quoted:\n%s", body)
+	}
+}
+
+func TestRenderSTIX(t *testing.T) {
+	entries := testEntries(t)
+	body, err := Render(STIX, entries, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle struct {
+		Type    string          `json:"type"`
+		ID      string          `json:"id"`
+		Objects []stixIndicator `json:"objects"`
+	}
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		t.Fatalf("feed isn't JSON: %v", err)
+	}
+	if bundle.Type != "bundle" || !strings.HasPrefix(bundle.ID, "bundle--") || len(bundle.Objects) != len(entries) {
+		t.Fatalf("bundle %s %s with %d objects", bundle.Type, bundle.ID, len(bundle.Objects))
+	}
+	patterns := map[string]string{}
+	for _, o := range bundle.Objects {
+		patterns[o.Name] = o.Pattern
+		if o.Created != "2024-04-30T08:00:00.000Z" || o.ValidFrom != o.Created {
+			t.Errorf("%s: created %s, valid from %s", o.Name, o.Created, o.ValidFrom)
+		}
+	}
+	wantPatterns := map[string]string{
+		"Scam domain: evil.com":                           "[domain-name:value = 'evil.com']",
+		"Scam url: https://shared.example.org/login?id=1": "[url:value = 'https://shared.example.org/login?id=1']",
+		"Scam prefix: host.example/phish":                 "[url:value LIKE '%host.example/phish%']",
+		"Scam pattern: *.bad.example/pay*":                "[url:value LIKE '%%.bad.example/pay%%']",
+		`Scam pattern: x\.example/'[0-9]+`:                `[url:value MATCHES 'x\\.example/\'[0-9]+']`,
+		"Scam url: http://a;b$c.example/":                 "[url:value = 'http://a;b$c.example/']",
+		"Scam url: https://whole.example.net/":            "[url:value = 'https://whole.example.net/']",
+	}
+	for name, want := range wantPatterns {
+		if got := patterns[name]; got != want {
+			t.Errorf("%s: pattern %s, want %s", name, got, want)
+		}
+	}
+
+	again, _ := Render(STIX, entries, updated)
+	if string(again) != string(body) {
+		t.Error("rendering the same list twice gave different STIX")
+	}
+}
+
+func TestRenderStable(t *testing.T) {
+	entries := testEntries(t)
+	reversed := slices.Clone(entries)
+	slices.Reverse(reversed)
+	for _, format := range Formats {
+		a, _ := Render(format, entries, updated)
+		b, _ := Render(format, reversed, updated)
+		if string(a) != string(b) {
+			t.Errorf("%s: output depends on entry order", format)
+		}
+		if ContentType(format) == "" {
+			t.Errorf("%s: no content type", format)
+		}
+	}
+	if _, err := Render("json", entries, updated); !errors.Is(err, ErrFormat) {
+		t.Errorf("Render(json) error = %v, want ErrFormat", err)
+	}
+}
+
+func TestZoneName(t *testing.T) {
+	tests := []struct{ host, want string }{
+		{"evil.com", "evil.com"},
+		{"under_score.evil.com", "under_score.evil.com"},
+		{"a;b.com", `a\;b.com`},
+		{"$origin.com", `\$origin.com`},
+		{`a"(b).com`, `a\"\(b\).com`},
+		{"a b.com", `a\032b.com`},
+		{"a\tb.com", `a\009b.com`},
+	}
+	for _, tt := range tests {
+		if got := zoneName(tt.host); got != tt.want {
+			t.Errorf("zoneName(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	at := time.Unix(1700000000, 5)
+	etag := ETag(Hosts, 3, at)
+	if etag != `"hosts-3-1700000000000000005"` {
+		t.Errorf("ETag = %s", etag)
+	}
+	for _, other := range []string{ETag(RPZ, 3, at), ETag(Hosts, 4, at), ETag(Hosts, 3, at.Add(time.Nanosecond))} {
+		if other == etag {
+			t.Errorf("ETag %s doesn't change with the format, version or entries", other)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const etag = `"hosts-3-5"`
+	lastModified := updated.Format(http.TimeFormat)
+	tests := []struct {
+		name                 string
+		ifNoneMatch, ifSince string
+		updated              time.Time
+		want                 bool
+	}{
+		{"no headers", "", "", updated, false},
+		{"etag matches", etag, "", updated, true},
+		{"weak etag matches", "W/" + etag, "", updated, true},
+		{"etag in a list", `"rpz-3-5", ` + etag, "", updated, true},
+		{"any etag", "*", "", updated, true},
+		{"stale etag", `"hosts-2-5"`, "", updated, false},
+		{"etag wins over date", `"hosts-2-5"`, lastModified, updated, false},
+		{"same second", "", lastModified, updated.Add(300 * time.Millisecond), true},
+		{"changed since", "", lastModified, updated.Add(time.Second), false},
+		{"older than since", "", lastModified, updated.Add(-time.Hour), true},
+		{"bad date", "", "yesterday", updated, false},
+		{"never updated", "", lastModified, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		if got := NotModified(tt.ifNoneMatch, tt.ifSince, etag, tt.updated); got != tt.want {
+			t.Errorf("%s: NotModified = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
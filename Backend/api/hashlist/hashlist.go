@@ -0,0 +1,398 @@
+// Package hashlist publishes the scam lists as hash prefixes, in the manner
+// of Safe Browsing, so clients such as the browser extension can check URLs
+// without sending them to us.
+//
+// Every listed URL, path prefix and host is reduced to expressions such as
+// "evil.com/" (the whole host), "evil.com/phish/" (everything under a path)
+// or "evil.com/login?id=1" (one URL), and each expression is hashed with
+// SHA-256. Clients keep the first four bytes of every hash and update them
+// with diffs between versions; when a URL they are about to visit produces a
+// matching prefix, they fetch the full hashes for that prefix and compare.
+//
+// The list lives in DB 3: "hashlist:prefixes" maps each prefix to its full
+// hashes, "hashlist:version" counts changes to the prefix set, and
+// "hashlist:diff:<version>" holds the prefixes added and removed by each
+// change, kept for diffRetention.
+package hashlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/safety"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	PrefixesKey = "hashlist:prefixes"
+	VersionKey  = "hashlist:version"
+	lockKey     = "hashlist:lock"
+)
+
+func diffKey(version int64) string {
+	return "hashlist:diff:" + strconv.FormatInt(version, 10)
+}
+
+const (
+	PrefixLen     = 4 // bytes
+	diffRetention = 7 * 24 * time.Hour
+	maxDiffs      = 500 // versions a client may be behind before it is sent the whole list
+	lockTTL       = 5 * time.Minute
+)
+
+var ErrPrefix = errors.New("prefix must be 8 hex characters")
+
+// Hash returns the SHA-256 of expression, hex encoded.
+func Hash(expression string) string {
+	sum := sha256.Sum256([]byte(expression))
+	return hex.EncodeToString(sum[:])
+}
+
+// Prefix returns the first PrefixLen bytes of a hex hash, still hex.
+func Prefix(hash string) string {
+	return hash[:PrefixLen*2]
+}
+
+// urlExpressions returns the expressions for a URL or path prefix key, as
+// canonical.Key gives it. A key with no path stands for the whole host.
+// Paths are stored without a trailing slash but may be visited with one, so
+// both spellings are listed; the one with the slash also stands for
+// everything below the path, which a client checks as it walks up the path.
+func urlExpressions(key string) []string {
+	switch {
+	case !strings.ContainsAny(key, "/?"):
+		return []string{key + "/"}
+	case strings.Contains(key, "?"):
+		return []string{key}
+	}
+	return []string{key, key + "/"}
+}
+
+// Expressions collects the expressions to publish: every verified URL and
+// scoped entry in DB 3 (patterns can't be hashed and are left out), and
+// every report in DB 2 rated at or above the warning threshold that no
+// reviewer has rejected.
+func Expressions(r2, r3 *redis.Client) ([]string, error) {
+	set := map[string]bool{}
+	entries, err := scamrules.All(r3)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		switch e.Scope {
+		case scamrules.ScopeURL:
+			for _, expr := range urlExpressions(canonical.Key(e.Value)) {
+				set[expr] = true
+			}
+		case scamrules.ScopePrefix:
+			for _, expr := range urlExpressions(e.Value) {
+				set[expr] = true
+			}
+		case scamrules.ScopeDomain:
+			set[e.Value+"/"] = true
+		}
+	}
+
+	threshold := safety.ConfigFromEnv().WarnThreshold
+	iter := r2.Scan(database.Ctx, 0, "*", 500).Iterator()
+	for iter.Next(database.Ctx) {
+		key := iter.Val()
+		if !scams.IsReportKey(key) {
+			continue
+		}
+		val, err := r2.Get(database.Ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(val), &data); err != nil {
+			continue
+		}
+		rating, _ := data["rating"].(float64)
+		if int(rating) < threshold || scams.State(data) == scams.StateRejected {
+			continue
+		}
+		// Records from before canonical keys carry their URL
+		if url, ok := data["url"].(string); ok && url != "" {
+			key = canonical.Key(url)
+		}
+		for _, expr := range urlExpressions(key) {
+			set[expr] = true
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(set))
+	for expr := range set {
+		out = append(out, expr)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// RebuildReport summarises a Rebuild.
+type RebuildReport struct {
+	Version  int64 `json:"version"`
+	Prefixes int   `json:"prefixes"`
+	Hashes   int   `json:"hashes"`
+	Added    int   `json:"added"`
+	Removed  int   `json:"removed"`
+}
+
+// Rebuild recomputes the list from the scam data and stores it. When the set
+// of prefixes changes, the version goes up by one and the change is kept as
+// that version's diff. Only one instance rebuilds at a time; the others
+// return the stored version untouched.
+func Rebuild(r2, r3 *redis.Client) (RebuildReport, error) {
+	var report RebuildReport
+	locked, err := r3.SetNX(database.Ctx, lockKey, os.Getpid(), lockTTL).Result()
+	if err != nil {
+		return report, err
+	}
+	if !locked {
+		report.Version, err = Version(r3)
+		return report, err
+	}
+	defer r3.Del(database.Ctx, lockKey)
+
+	expressions, err := Expressions(r2, r3)
+	if err != nil {
+		return report, err
+	}
+	next := map[string][]string{}
+	for _, expr := range expressions {
+		hash := Hash(expr)
+		next[Prefix(hash)] = append(next[Prefix(hash)], hash)
+	}
+	current, err := r3.HGetAll(database.Ctx, PrefixesKey).Result()
+	if err != nil {
+		return report, err
+	}
+	version, err := Version(r3)
+	if err != nil {
+		return report, err
+	}
+
+	var added, removed []string
+	changed := map[string]interface{}{}
+	for prefix, hashes := range next {
+		slices.Sort(hashes)
+		val := strings.Join(hashes, ",")
+		old, ok := current[prefix]
+		if !ok {
+			added = append(added, prefix)
+		}
+		if val != old {
+			changed[prefix] = val
+		}
+		report.Hashes += len(hashes)
+	}
+	for prefix := range current {
+		if _, ok := next[prefix]; !ok {
+			removed = append(removed, prefix)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+
+	_, err = r3.TxPipelined(database.Ctx, func(pipe redis.Pipeliner) error {
+		if len(changed) > 0 {
+			pipe.HSet(database.Ctx, PrefixesKey, changed)
+		}
+		if len(removed) > 0 {
+			pipe.HDel(database.Ctx, PrefixesKey, removed...)
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			version++
+			addJSON, _ := json.Marshal(nonNil(added))
+			removeJSON, _ := json.Marshal(nonNil(removed))
+			pipe.HSet(database.Ctx, diffKey(version), "add", addJSON, "remove", removeJSON)
+			pipe.Expire(database.Ctx, diffKey(version), diffRetention)
+			pipe.Set(database.Ctx, VersionKey, version, 0)
+		}
+		return nil
+	})
+	report.Version, report.Prefixes = version, len(next)
+	report.Added, report.Removed = len(added), len(removed)
+	return report, err
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// Version returns the current version, 0 before the first build.
+func Version(r3 *redis.Client) (int64, error) {
+	v, err := r3.Get(database.Ctx, VersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return v, err
+}
+
+// Update is what a client needs to bring its prefix list to Version. When
+// Reset is set, Additions is the whole list and the client should drop what
+// it had.
+type Update struct {
+	Version   string   `json:"version"`
+	Reset     bool     `json:"reset"`
+	Additions []string `json:"additions"`
+	Removals  []string `json:"removals"`
+}
+
+// Updates returns the changes since the version token since. Unknown,
+// future or expired tokens get the whole list.
+func Updates(r3 *redis.Client, since string) (*Update, error) {
+	version, err := Version(r3)
+	if err != nil {
+		return nil, err
+	}
+	update := &Update{Version: strconv.FormatInt(version, 10), Additions: []string{}, Removals: []string{}}
+	from, err := strconv.ParseInt(since, 10, 64)
+	if err != nil || from < 0 || from > version || version-from > maxDiffs {
+		return full(r3, update)
+	}
+	if from == version {
+		return update, nil
+	}
+
+	pipe := r3.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, 0, version-from)
+	for v := from + 1; v <= version; v++ {
+		cmds = append(cmds, pipe.HGetAll(database.Ctx, diffKey(v)))
+	}
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return nil, err
+	}
+	diffs := make([]map[string]string, len(cmds))
+	for i, cmd := range cmds {
+		diffs[i] = cmd.Val()
+	}
+	additions, removals, ok := mergeDiffs(diffs)
+	if !ok {
+		return full(r3, update)
+	}
+	update.Additions, update.Removals = additions, removals
+	return update, nil
+}
+
+// mergeDiffs folds consecutive diffs, oldest first, into one set of
+// additions and removals; a prefix added and then removed again cancels out.
+// It reports false when a diff is missing, meaning it expired and the client
+// needs the whole list.
+func mergeDiffs(diffs []map[string]string) (additions, removals []string, ok bool) {
+	added, removed := map[string]bool{}, map[string]bool{}
+	for _, diff := range diffs {
+		if len(diff) == 0 {
+			return nil, nil, false
+		}
+		var add, remove []string
+		json.Unmarshal([]byte(diff["add"]), &add)
+		json.Unmarshal([]byte(diff["remove"]), &remove)
+		for _, p := range add {
+			if removed[p] {
+				delete(removed, p)
+			} else {
+				added[p] = true
+			}
+		}
+		for _, p := range remove {
+			if added[p] {
+				delete(added, p)
+			} else {
+				removed[p] = true
+			}
+		}
+	}
+	additions, removals = []string{}, []string{}
+	for p := range added {
+		additions = append(additions, p)
+	}
+	for p := range removed {
+		removals = append(removals, p)
+	}
+	slices.Sort(additions)
+	slices.Sort(removals)
+	return additions, removals, true
+}
+
+func full(r3 *redis.Client, update *Update) (*Update, error) {
+	prefixes, err := r3.HKeys(database.Ctx, PrefixesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(prefixes)
+	update.Reset, update.Additions = true, prefixes
+	return update, nil
+}
+
+// FullHashes returns the full hashes listed under each prefix, an empty list
+// for prefixes that aren't listed.
+func FullHashes(r3 *redis.Client, prefixes []string) (map[string][]string, error) {
+	for i, p := range prefixes {
+		p = strings.ToLower(p)
+		if len(p) != PrefixLen*2 {
+			return nil, ErrPrefix
+		}
+		if _, err := hex.DecodeString(p); err != nil {
+			return nil, ErrPrefix
+		}
+		prefixes[i] = p
+	}
+	vals, err := r3.HMGet(database.Ctx, PrefixesKey, prefixes...).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]string, len(prefixes))
+	for i, p := range prefixes {
+		out[p] = []string{}
+		if s, ok := vals[i].(string); ok && s != "" {
+			out[p] = strings.Split(s, ",")
+		}
+	}
+	return out, nil
+}
+
+// RefreshInterval is how often Start rebuilds the list, read from
+// HASHLIST_REFRESH_SECONDS (default 300). Clients are told to poll no more
+// often than this.
+func RefreshInterval() time.Duration {
+	if n, err := strconv.Atoi(os.Getenv("HASHLIST_REFRESH_SECONDS")); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// Start rebuilds the list now and every RefreshInterval in the background.
+// Call it once at startup.
+func Start() {
+	go func() {
+		r2 := database.CreateClient(2)
+		r3 := database.CreateClient(3)
+		ticker := time.NewTicker(RefreshInterval())
+		defer ticker.Stop()
+		for {
+			if _, err := Rebuild(r2, r3); err != nil {
+				log.Println("hashlist: rebuild failed:", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
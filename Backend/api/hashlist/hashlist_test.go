@@ -0,0 +1,96 @@
+package hashlist
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestURLExpressions(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"evil.com", []string{"evil.com/"}},
+		{"evil.com:8443", []string{"evil.com:8443/"}},
+		{"evil.com/phish", []string{"evil.com/phish", "evil.com/phish/"}},
+		{"evil.com/a/b", []string{"evil.com/a/b", "evil.com/a/b/"}},
+		{"evil.com/login?id=1", []string{"evil.com/login?id=1"}},
+		{"evil.com?id=1", []string{"evil.com?id=1"}},
+	}
+	for _, tt := range tests {
+		if got := urlExpressions(tt.key); !slices.Equal(got, tt.want) {
+			t.Errorf("urlExpressions(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestHashPrefix(t *testing.T) {
+	// SHA-256 of the empty string
+	const empty = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := Hash(""); got != empty {
+		t.Errorf("Hash(\"\") = %q, want %q", got, empty)
+	}
+	if got := Prefix(empty); got != "e3b0c442" {
+		t.Errorf("Prefix = %q, want e3b0c442", got)
+	}
+	h := Hash("evil.com/")
+	if len(h) != 64 || h != strings.ToLower(h) || Prefix(h) != h[:PrefixLen*2] {
+		t.Errorf("Hash(evil.com/) = %q, want 64 lower-case hex characters", h)
+	}
+}
+
+func diff(add, remove string) map[string]string {
+	return map[string]string{"add": add, "remove": remove}
+}
+
+func TestMergeDiffs(t *testing.T) {
+	tests := []struct {
+		name          string
+		diffs         []map[string]string
+		add, remove   []string
+		wantFullReset bool
+	}{
+		{"none", nil, []string{}, []string{}, false},
+		{
+			"one diff",
+			[]map[string]string{diff(`["bbbbbbbb","aaaaaaaa"]`, `["cccccccc"]`)},
+			[]string{"aaaaaaaa", "bbbbbbbb"}, []string{"cccccccc"}, false,
+		},
+		{
+			"removal after an add cancels out",
+			[]map[string]string{diff(`["aaaaaaaa","bbbbbbbb"]`, `[]`), diff(`[]`, `["aaaaaaaa"]`)},
+			[]string{"bbbbbbbb"}, []string{}, false,
+		},
+		{
+			"add after a removal cancels out",
+			[]map[string]string{diff(`[]`, `["aaaaaaaa","cccccccc"]`), diff(`["aaaaaaaa"]`, `[]`)},
+			[]string{}, []string{"cccccccc"}, false,
+		},
+		{
+			"add, remove, add again",
+			[]map[string]string{diff(`["aaaaaaaa"]`, `[]`), diff(`[]`, `["aaaaaaaa"]`), diff(`["aaaaaaaa"]`, `[]`)},
+			[]string{"aaaaaaaa"}, []string{}, false,
+		},
+		{
+			"gap where a diff expired",
+			[]map[string]string{diff(`["aaaaaaaa"]`, `[]`), {}, diff(`[]`, `["bbbbbbbb"]`)},
+			nil, nil, true,
+		},
+		{
+			"gap in the first version",
+			[]map[string]string{nil, diff(`["aaaaaaaa"]`, `[]`)},
+			nil, nil, true,
+		},
+	}
+	for _, tt := range tests {
+		add, remove, ok := mergeDiffs(tt.diffs)
+		if ok == tt.wantFullReset {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, !tt.wantFullReset)
+			continue
+		}
+		if !slices.Equal(add, tt.add) || !slices.Equal(remove, tt.remove) {
+			t.Errorf("%s: got +%q -%q, want +%q -%q", tt.name, add, remove, tt.add, tt.remove)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package importer loads external phishing feeds into the scam reports in
+// DB 2. It reads the files the feeds publish: PhishTank's JSON or CSV dump,
+// OpenPhish's plain list of URLs and URLhaus's CSV export.
+//
+// Each URL becomes a report from the feed, merged with any reports already
+// on it and tagged with the feed's name. A feed counts once per URL, so
+// importing the same file again only adds to the skipped count.
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/go-redis/redis/v8"
+)
+
+// Sources understood.
+const (
+	PhishTank = "phishtank"
+	OpenPhish = "openphish"
+	URLhaus   = "urlhaus"
+)
+
+var Sources = []string{PhishTank, OpenPhish, URLhaus}
+
+var (
+	ErrSource = errors.New("source must be one of phishtank, openphish or urlhaus")
+	ErrFormat = errors.New("file isn't in the source's format")
+)
+
+// Cap on how much of a feed's description is kept on a report.
+const maxDescriptionLen = 500
+
+// Item is one URL read from a feed.
+type Item struct {
+	URL         string
+	Category    string
+	Description string
+}
+
+// Result counts what an import did with each URL: New ones had no report
+// yet, Merged ones were added to an existing report, and Skipped ones were
+// invalid, already imported from the feed, or rejected by a reviewer
+// recently.
+type Result struct {
+	Source  string `json:"source"`
+	New     int    `json:"new"`
+	Merged  int    `json:"merged"`
+	Skipped int    `json:"skipped"`
+}
+
+// File imports the feed file at path.
+func File(r2 *redis.Client, source, path string) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{Source: source}, err
+	}
+	defer f.Close()
+	return Import(r2, source, f)
+}
+
+// Import reads a feed in source's format from in and imports every URL in
+// it. The counts so far are returned along with any error; errors reading
+// the feed wrap ErrFormat, others come from the database.
+func Import(r2 *redis.Client, source string, in io.Reader) (Result, error) {
+	result := Result{Source: source}
+	var storeErr error
+	err := Parse(source, in, func(item Item) error {
+		if _, err := canonical.URL(item.URL); err != nil {
+			result.Skipped++
+			return nil
+		}
+		if len(item.Description) > maxDescriptionLen {
+			item.Description = strings.ToValidUTF8(item.Description[:maxDescriptionLen], "")
+		}
+		created, err := scams.Import(r2, item.URL, source, scams.Report{
+			Description: item.Description,
+			Category:    item.Category,
+			Source:      source,
+		})
+		switch {
+		case err == scams.ErrImported || err == scams.ErrCooldown:
+			result.Skipped++
+		case err != nil:
+			storeErr = err
+			return err
+		case created:
+			result.New++
+		default:
+			result.Merged++
+		}
+		return nil
+	})
+	if err != nil && err != ErrSource && storeErr == nil {
+		err = fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return result, err
+}
+
+// Parse reads a feed in source's format, calling fn with each URL in it.
+// URLs are passed on as the feed has them, valid or not.
+func Parse(source string, in io.Reader, fn func(Item) error) error {
+	switch source {
+	case PhishTank:
+		return phishTank(bufio.NewReader(in), fn)
+	case OpenPhish:
+		return openPhish(in, fn)
+	case URLhaus:
+		return urlhaus(in, fn)
+	}
+	return ErrSource
+}
+
+// phishTank reads the JSON or CSV dump, telling them apart by the first
+// character. Only entries PhishTank has verified are imported.
+func phishTank(in *bufio.Reader, fn func(Item) error) error {
+	first, err := firstByte(in)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if first == '[' {
+		return phishTankJSON(in, fn)
+	}
+	return readCSV(in, func(row map[string]string) error {
+		if row["verified"] == "no" {
+			return nil
+		}
+		return fn(phishTankItem(row["url"], row["target"]))
+	})
+}
+
+// firstByte returns the first byte of in that isn't white space or a byte
+// order mark, leaving it unread.
+func firstByte(in *bufio.Reader) (byte, error) {
+	if bom, _ := in.Peek(3); string(bom) == "\xef\xbb\xbf" {
+		in.Discard(3)
+	}
+	for {
+		b, err := in.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		in.ReadByte()
+	}
+}
+
+func phishTankJSON(in io.Reader, fn func(Item) error) error {
+	dec := json.NewDecoder(in)
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("phishtank: %w", err)
+	}
+	for dec.More() {
+		var entry struct {
+			URL      string      `json:"url"`
+			Target   string      `json:"target"`
+			Verified interface{} `json:"verified"`
+		}
+		if err := dec.Decode(&entry); err != nil {
+			return fmt.Errorf("phishtank: %w", err)
+		}
+		if entry.Verified == "no" || entry.Verified == false {
+			continue
+		}
+		if err := fn(phishTankItem(entry.URL, entry.Target)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func phishTankItem(url, target string) Item {
+	item := Item{URL: strings.TrimSpace(url), Category: "phishing", Description: "Listed by PhishTank"}
+	if target != "" && target != "Other" {
+		item.Description += ", targeting " + target
+	}
+	return item
+}
+
+func openPhish(in io.Reader, fn func(Item) error) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(Item{URL: line, Category: "phishing", Description: "Listed by OpenPhish"}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// urlhausColumns are the columns of the URLhaus export, whose header line is
+// commented out along with the rest of its preamble.
+var urlhausColumns = []string{"id", "dateadded", "url", "url_status", "last_online", "threat", "tags", "urlhaus_link", "reporter"}
+
+func urlhaus(in io.Reader, fn func(Item) error) error {
+	r := csv.NewReader(in)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("urlhaus: %w", err)
+		}
+		row := map[string]string{}
+		for i, col := range urlhausColumns {
+			if i < len(record) {
+				row[col] = strings.TrimSpace(record[i])
+			}
+		}
+		if row["url"] == "url" {
+			continue // an uncommented header
+		}
+		item := Item{URL: row["url"], Category: "malware", Description: "Listed by URLhaus"}
+		if row["threat"] != "" {
+			item.Description += ": " + row["threat"]
+		}
+		if row["tags"] != "" && row["tags"] != "None" {
+			item.Description += " (" + row["tags"] + ")"
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// readCSV reads a CSV file with a header line, calling fn with each row
+// keyed by column name.
+func readCSV(in io.Reader, fn func(map[string]string) error) error {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row := map[string]string{}
+		for i, col := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(col)] = strings.TrimSpace(record[i])
+			}
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, source, input string
+		want                []string
+	}{
+		{
+			"phishtank json", PhishTank,
+			`[{"url":"http://a.example/","target":"PayPal","verified":"yes"},{"url":"http://b.example/","verified":"no"},{"url":" http://c.example/ ","verified":true}]`,
+			[]string{"http://a.example/", "http://c.example/"},
+		},
+		{
+			"phishtank json with bom", PhishTank,
+			"\xef\xbb\xbf\n [{\"url\":\"http://a.example/\"}]",
+			[]string{"http://a.example/"},
+		},
+		{
+			"phishtank csv", PhishTank,
+			"phish_id,url,verified,target\n1,http://a.example/,yes,Other\n2,http://b.example/,no,Other\n",
+			[]string{"http://a.example/"},
+		},
+		{"phishtank empty", PhishTank, "", nil},
+		{
+			"openphish", OpenPhish,
+			"# comment\nhttp://a.example/\n\n  http://b.example/x  \n",
+			[]string{"http://a.example/", "http://b.example/x"},
+		},
+		{
+			"urlhaus", URLhaus,
+			"# URLhaus export\n# id,dateadded,url,url_status,last_online,threat,tags,urlhaus_link,reporter\n" +
+				`"1","2024-01-01 00:00:00","http://a.example/bin","online","","malware_download","elf,mirai","https://urlhaus.abuse.ch/url/1/","anon"` + "\n",
+			[]string{"http://a.example/bin"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := Parse(tt.source, strings.NewReader(tt.input), func(item Item) error {
+			got = append(got, item.URL)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: Parse returned error %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Parse read %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDescriptions(t *testing.T) {
+	var items []Item
+	collect := func(item Item) error {
+		items = append(items, item)
+		return nil
+	}
+	Parse(PhishTank, strings.NewReader(`[{"url":"http://a.example/","target":"PayPal"}]`), collect)
+	Parse(URLhaus, strings.NewReader(`"1","","http://b.example/","online","","malware_download","elf","",""`), collect)
+	want := []Item{
+		{URL: "http://a.example/", Category: "phishing", Description: "Listed by PhishTank, targeting PayPal"},
+		{URL: "http://b.example/", Category: "malware", Description: "Listed by URLhaus: malware_download (elf)"},
+	}
+	if !slices.Equal(items, want) {
+		t.Errorf("items = %+v, want %+v", items, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if err := Parse("spamhaus", strings.NewReader(""), func(Item) error { return nil }); err != ErrSource {
+		t.Errorf("unknown source: error = %v, want ErrSource", err)
+	}
+	if err := Parse(PhishTank, strings.NewReader(`[{"url": `), func(Item) error { return nil }); err == nil {
+		t.Error("truncated PhishTank JSON: no error")
+	}
+	stop := errors.New("stop")
+	n := 0
+	err := Parse(OpenPhish, strings.NewReader("http://a.example/\nhttp://b.example/\n"), func(Item) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("callback error: got %v after %d items, want stop after 1", err, n)
+	}
+}
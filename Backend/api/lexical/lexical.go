@@ -0,0 +1,271 @@
+// Package lexical rates how suspicious a URL looks from its text alone, with
+// no network access, so URLs nobody has reported yet still get a risk score.
+//
+// Each feature that fires adds points and a reason; the score is their sum,
+// capped at 100. The features are the usual marks of phishing URLs: a raw IP
+// for a host, user info before the host ("paypal.com@evil.com"), punycode,
+// deep subdomains, cheap TLDs favoured by abuse, very long paths, a brand
+// name on a domain the brand doesn't own, and links wrapped in other short
+// links or redirects.
+//
+// Brand names come from a built-in list of brands phishers commonly imitate
+// and from the protected-brand list admins keep (package brands); a name on
+// either list only counts on the domains it lists.
+package lexical
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/brands"
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Finding is one feature that fired.
+type Finding struct {
+	Feature string `json:"feature"`
+	Points  int    `json:"points"`
+	Reason  string `json:"reason"`
+}
+
+// Assessment is the score for one URL and the findings behind it.
+type Assessment struct {
+	Score    int       `json:"score"`
+	Findings []Finding `json:"findings"`
+}
+
+// Reasons returns the reason of every finding.
+func (a Assessment) Reasons() []string {
+	reasons := make([]string, len(a.Findings))
+	for i, f := range a.Findings {
+		reasons[i] = f.Reason
+	}
+	return reasons
+}
+
+// Features and their points.
+const (
+	FeatureIPHost      = "ip_host"
+	FeatureUserInfo    = "userinfo"
+	FeaturePunycode    = "punycode"
+	FeatureSubdomains  = "subdomains"
+	FeatureTLD         = "suspicious_tld"
+	FeatureLongPath    = "long_path"
+	FeatureBrand       = "brand"
+	FeatureShortener   = "shortener"
+	FeatureEmbeddedURL = "embedded_url"
+)
+
+var points = map[string]int{
+	FeatureIPHost:      35,
+	FeatureUserInfo:    35,
+	FeaturePunycode:    25,
+	FeatureSubdomains:  15,
+	FeatureTLD:         15,
+	FeatureLongPath:    10,
+	FeatureBrand:       30,
+	FeatureShortener:   20,
+	FeatureEmbeddedURL: 10,
+}
+
+const (
+	maxSubdomains = 3   // labels in front of the registrable domain, "www" aside
+	maxPathLen    = 100 // escaped path and query
+	maxScore      = 100
+)
+
+// suspiciousTLDs are top-level domains that abuse reports single out for
+// their share of phishing and malware registrations.
+var suspiciousTLDs = map[string]bool{
+	"zip": true, "mov": true, "xyz": true, "top": true, "tk": true,
+	"ml": true, "ga": true, "cf": true, "gq": true, "work": true,
+	"click": true, "country": true, "kim": true, "loan": true, "men": true,
+	"gdn": true, "racing": true, "review": true, "stream": true, "download": true,
+	"icu": true, "cyou": true, "rest": true, "buzz": true, "monster": true,
+	"sbs": true, "cfd": true, "bond": true,
+}
+
+// builtinBrands maps brand names phishers imitate to the registrable domains
+// the brand itself uses. Admins add their own through package brands.
+var builtinBrands = map[string][]string{
+	"paypal":        {"paypal.com", "paypal.me"},
+	"apple":         {"apple.com"},
+	"icloud":        {"icloud.com", "apple.com"},
+	"google":        {"google.com", "goo.gl", "youtube.com"},
+	"microsoft":     {"microsoft.com", "live.com", "office.com"},
+	"office365":     {"office.com", "microsoft.com"},
+	"outlook":       {"outlook.com", "live.com", "office.com"},
+	"amazon":        {"amazon.com", "amazon.co.uk", "amazon.de", "amazon.in"},
+	"netflix":       {"netflix.com"},
+	"facebook":      {"facebook.com", "fb.com"},
+	"instagram":     {"instagram.com"},
+	"whatsapp":      {"whatsapp.com"},
+	"linkedin":      {"linkedin.com"},
+	"dropbox":       {"dropbox.com"},
+	"docusign":      {"docusign.com", "docusign.net"},
+	"chase":         {"chase.com"},
+	"wellsfargo":    {"wellsfargo.com"},
+	"bankofamerica": {"bankofamerica.com"},
+	"citibank":      {"citibank.com", "citi.com"},
+	"hsbc":          {"hsbc.com", "hsbc.co.uk"},
+	"coinbase":      {"coinbase.com"},
+	"binance":       {"binance.com"},
+	"metamask":      {"metamask.io"},
+	"dhl":           {"dhl.com", "dhl.de"},
+	"fedex":         {"fedex.com"},
+	"usps":          {"usps.com"},
+	"steam":         {"steampowered.com", "steamcommunity.com"},
+}
+
+// shorteners are public URL shorteners. A short link to another short link
+// hides the real destination from anyone checking ours.
+var shorteners = map[string]bool{
+	"bit.ly": true, "tinyurl.com": true, "t.co": true, "goo.gl": true,
+	"ow.ly": true, "is.gd": true, "v.gd": true, "buff.ly": true,
+	"cutt.ly": true, "rebrand.ly": true, "shorturl.at": true, "tiny.cc": true,
+	"rb.gy": true, "s.id": true, "lnkd.in": true, "t.ly": true,
+	"bl.ink": true, "short.io": true, "tr.im": true, "x.co": true,
+}
+
+// Score rates raw. URLs that don't parse score 0 with no findings. The
+// protected-brand list is read through its cache; when it can't be read,
+// only the built-in brands are checked.
+func Score(raw string) Assessment {
+	protected, _ := brands.Load()
+	return score(raw, protected)
+}
+
+func score(raw string, protected *brands.Set) Assessment {
+	a := Assessment{Findings: []Finding{}}
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	host := canonical.Host(raw)
+	if err != nil || host == "" {
+		return a
+	}
+	add := func(feature, reason string) {
+		a.Findings = append(a.Findings, Finding{Feature: feature, Points: points[feature], Reason: reason})
+		a.Score += points[feature]
+	}
+
+	if u.User != nil {
+		add(FeatureUserInfo, "The address has text before an \"@\", so the real host is "+host)
+	}
+	if isIP(host) {
+		add(FeatureIPHost, "The host is a bare IP address")
+		return finish(a)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			add(FeaturePunycode, "The host uses international characters that can imitate other letters")
+			break
+		}
+	}
+
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		registrable = host
+	}
+	var subdomains []string
+	if sub := strings.TrimSuffix(host, registrable); sub != "" {
+		subdomains = strings.Split(strings.TrimSuffix(sub, "."), ".")
+	}
+	if n := len(subdomains) - countWWW(subdomains); n > maxSubdomains {
+		add(FeatureSubdomains, "The host has an unusual number of subdomains")
+	}
+	tld := host[strings.LastIndex(host, ".")+1:]
+	if suspiciousTLDs[tld] {
+		add(FeatureTLD, "The ."+tld+" domain ending is common among scam sites")
+	}
+	if len(u.EscapedPath())+len(u.RawQuery) > maxPathLen {
+		add(FeatureLongPath, "The path is unusually long")
+	}
+	if brand := imitatedBrand(registrable, subdomains, protected); brand != "" {
+		add(FeatureBrand, "The address mentions "+brand+" but isn't on a "+brand+" domain")
+	}
+	if shorteners[strings.TrimPrefix(host, "www.")] {
+		add(FeatureShortener, "The destination is itself a short link on "+strings.TrimPrefix(host, "www."))
+	}
+	if embedsURL(u, host) {
+		add(FeatureEmbeddedURL, "The address carries another web address to send you on to")
+	}
+	return finish(a)
+}
+
+func finish(a Assessment) Assessment {
+	a.Score = min(a.Score, maxScore)
+	return a
+}
+
+// isIP reports whether host is an IP address, including the single-number,
+// octal and hex spellings browsers accept, such as 3232235777 or 0xC0A80101.
+func isIP(host string) bool {
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return true
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) > 4 {
+		return false
+	}
+	for _, label := range labels {
+		digits := "0123456789"
+		if strings.HasPrefix(label, "0x") {
+			label, digits = label[2:], "0123456789abcdef"
+		} else if label == "" {
+			return false
+		}
+		if strings.Trim(label, digits) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func countWWW(labels []string) int {
+	n := 0
+	for _, l := range labels {
+		if l == "www" {
+			n++
+		}
+	}
+	return n
+}
+
+// imitatedBrand returns a brand named in the subdomains or the registrable
+// domain's own label, e.g. "paypal.secure-login.com" or "paypal-login.com",
+// when the registrable domain isn't one the brand uses.
+func imitatedBrand(registrable string, subdomains []string, protected *brands.Set) string {
+	labels := append([]string{strings.SplitN(registrable, ".", 2)[0]}, subdomains...)
+	for _, label := range labels {
+		for _, token := range strings.Split(label, "-") {
+			domains := slices.Concat(builtinBrands[token], protected.Domains(token))
+			if len(domains) > 0 && !slices.Contains(domains, registrable) {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+// embedsURL reports whether a query value of u is a web address on another
+// host, as open redirects and tracking wrappers carry them.
+func embedsURL(u *url.URL, host string) bool {
+	for _, values := range u.Query() {
+		for _, v := range values {
+			lower := strings.ToLower(v)
+			if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+				continue
+			}
+			if other := canonical.Host(v); other != "" && other != host {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package lexical
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func features(a Assessment) []string {
+	out := make([]string, len(a.Findings))
+	for i, f := range a.Findings {
+		out[i] = f.Feature
+	}
+	return out
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://www.paypal.com/signin", nil},
+		{"https://example.com/", nil},
+		{"http://192.168.1.1/login", []string{FeatureIPHost}},
+		{"http://3232235777/", []string{FeatureIPHost}},
+		{"http://0xC0A80101/", []string{FeatureIPHost}},
+		{"http://[2001:db8::1]/", []string{FeatureIPHost}},
+		{"http://paypal.com@evil.com/", []string{FeatureUserInfo}},
+		{"http://xn--pypal-4ve.com/", []string{FeaturePunycode}},
+		{"http://a.b.c.d.evil.com/", []string{FeatureSubdomains}},
+		{"http://www.a.b.c.evil.com/", nil},
+		{"http://free-prizes.xyz/", []string{FeatureTLD}},
+		{"http://evil.com/" + strings.Repeat("a", 120), []string{FeatureLongPath}},
+		{"http://paypal-login.com/", []string{FeatureBrand}},
+		{"http://paypal.secure-check.com/", []string{FeatureBrand}},
+		{"http://bit.ly/abc", []string{FeatureShortener}},
+		{"http://example.com/go?to=https://evil.com/", []string{FeatureEmbeddedURL}},
+		{"http://example.com/go?to=https://example.com/", nil},
+		{"not a url", nil},
+	}
+	for _, tt := range tests {
+		got := features(score(tt.url, nil))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("score(%q) features = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestScoreSumsAndCaps(t *testing.T) {
+	a := score("http://paypal-login.xyz/", nil)
+	if want := points[FeatureTLD] + points[FeatureBrand]; a.Score != want {
+		t.Errorf("score = %d, want %d", a.Score, want)
+	}
+	if len(a.Reasons()) != len(a.Findings) {
+		t.Errorf("Reasons() has %d entries for %d findings", len(a.Reasons()), len(a.Findings))
+	}
+
+	a = score("http://paypal@a.b.c.d.paypal-login.xyz/r?u=https://bit.ly/x&p="+strings.Repeat("x", 120), nil)
+	if a.Score != maxScore {
+		t.Errorf("score = %d, want it capped at %d", a.Score, maxScore)
+	}
+}
+
+func TestIsIP(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1":  true,
+		"2130706433": true,
+		"0x7f.1":     true,
+		"[::1]":      true,
+		"1.2.3.4.5":  false,
+		"1.2.3.com":  false,
+		"evil.com":   false,
+		"0xfoo.com":  false,
+		"1..2":       false,
+	}
+	for host, want := range tests {
+		if got := isIP(host); got != want {
+			t.Errorf("isIP(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
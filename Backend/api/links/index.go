@@ -0,0 +1,380 @@
+package links
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/go-redis/redis/v8"
+)
+
+// Links are indexed by owner (two sorted sets, scored by creation time and
+// by expiry), by tag, and by destination URL and host (plain sets), so
+// listing and takedowns never have to scan the keyspace. Index entries for
+// expired, deleted or edited links are pruned lazily when a lookup finds
+// they no longer match.
+func ownerIndexKey(owner string) string {
+	return "idx:owner:" + owner
+}
+
+func ownerExpiryIndexKey(owner string) string {
+	return "idx:owner-expiry:" + owner
+}
+
+func tagIndexKey(tag string) string {
+	return "idx:tag:" + tag
+}
+
+func destIndexKey(url string) string {
+	return "idx:dest:" + DestinationKey(url)
+}
+
+func hostIndexKey(host string) string {
+	return "idx:host:" + host
+}
+
+// DestinationKey reduces a URL to the form the destination index uses, its
+// canonical key.
+func DestinationKey(rawURL string) string {
+	return canonical.Key(rawURL)
+}
+
+// Host returns the canonical host of a URL without a leading "www.".
+func Host(rawURL string) string {
+	return strings.TrimPrefix(canonical.Host(rawURL), "www.")
+}
+
+// NeverExpires is the expiry score of links without an expiry, so they sort
+// after every expiring one. It is exact as a sorted set score.
+const NeverExpires = 1 << 53
+
+// ExpiryScore returns the score link has in its owner's expiry index.
+func ExpiryScore(link *models.Link) int64 {
+	if link.ExpiresAt.IsZero() {
+		return NeverExpires
+	}
+	return link.ExpiresAt.Unix()
+}
+
+func writeIndexes(pipe redis.Pipeliner, link *models.Link) {
+	if link.Owner != "" {
+		pipe.ZAdd(database.Ctx, ownerIndexKey(link.Owner), &redis.Z{
+			Score:  float64(link.CreatedAt.Unix()),
+			Member: link.ID,
+		})
+		pipe.ZAdd(database.Ctx, ownerExpiryIndexKey(link.Owner), &redis.Z{
+			Score:  float64(ExpiryScore(link)),
+			Member: link.ID,
+		})
+	}
+	for _, tag := range link.Tags {
+		pipe.SAdd(database.Ctx, tagIndexKey(tag), link.ID)
+	}
+	pipe.SAdd(database.Ctx, destIndexKey(link.URL), link.ID)
+	if host := Host(link.URL); host != "" {
+		pipe.SAdd(database.Ctx, hostIndexKey(host), link.ID)
+	}
+	if link.Status == models.LinkHeld {
+		pipe.ZAddNX(database.Ctx, HeldKey, &redis.Z{Score: float64(time.Now().Unix()), Member: link.ID})
+	}
+}
+
+func removeIndexes(pipe redis.Pipeliner, link *models.Link) {
+	if link.Owner != "" {
+		pipe.ZRem(database.Ctx, ownerIndexKey(link.Owner), link.ID)
+		pipe.ZRem(database.Ctx, ownerExpiryIndexKey(link.Owner), link.ID)
+	}
+	for _, tag := range link.Tags {
+		pipe.SRem(database.Ctx, tagIndexKey(tag), link.ID)
+	}
+	pipe.SRem(database.Ctx, destIndexKey(link.URL), link.ID)
+	if host := Host(link.URL); host != "" {
+		pipe.SRem(database.Ctx, hostIndexKey(host), link.ID)
+	}
+	pipe.ZRem(database.Ctx, HeldKey, link.ID)
+}
+
+// Delete removes link and its index entries.
+func Delete(r *redis.Client, link *models.Link) error {
+	pipe := r.TxPipeline()
+	pipe.Del(database.Ctx, link.ID)
+	removeIndexes(pipe, link)
+	_, err := pipe.Exec(database.Ctx)
+	return err
+}
+
+// Owned returns every live link owned by owner, optionally restricted to
+// those carrying tag.
+func Owned(r *redis.Client, owner, tag string) ([]*models.Link, error) {
+	var ids []string
+	var err error
+	if tag == "" {
+		ids, err = r.ZRange(database.Ctx, ownerIndexKey(owner), 0, -1).Result()
+	} else {
+		ids, err = r.ZInter(database.Ctx, &redis.ZStore{
+			Keys: []string{ownerIndexKey(owner), tagIndexKey(tag)},
+		}).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := r.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(database.Ctx, id)
+	}
+	if len(ids) > 0 {
+		if _, err := pipe.Exec(database.Ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*models.Link, 0, len(ids))
+	var stale []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 || fields["owner"] != owner {
+			stale = append(stale, ids[i])
+			continue
+		}
+		result = append(result, decode(ids[i], fields))
+	}
+	if len(stale) > 0 {
+		r.ZRem(database.Ctx, ownerIndexKey(owner), stale...)
+		r.ZRem(database.Ctx, ownerExpiryIndexKey(owner), stale...)
+		if tag != "" {
+			r.SRem(database.Ctx, tagIndexKey(tag), stale...)
+		}
+	}
+	return result, nil
+}
+
+// Orders OwnedPage can page through, each backed by an owner index.
+const (
+	ByCreated = "created"
+	ByExpiry  = "expires"
+)
+
+// ownedBatch is how many index entries OwnedPage reads at a time.
+const ownedBatch = 100
+
+// OwnedPage returns up to limit live links owned by owner that pass keep,
+// read in order from the owner index for order (ByCreated or ByExpiry),
+// newest or latest first when desc is set. With afterID set the page starts
+// right after the link with that ID and score afterScore, so links added in
+// the meantime don't shift it. More reports whether any link follows.
+func OwnedPage(r *redis.Client, owner, order string, desc bool, afterScore int64, afterID string, limit int, keep func(*models.Link) bool) (page []*models.Link, more bool, err error) {
+	key := ownerIndexKey(owner)
+	if order == ByExpiry {
+		key = ownerExpiryIndexKey(owner)
+	}
+	var stale []interface{}
+	defer func() {
+		// Pruned at the end, so the offsets below stay valid
+		if len(stale) > 0 {
+			r.ZRem(database.Ctx, ownerIndexKey(owner), stale...)
+			r.ZRem(database.Ctx, ownerExpiryIndexKey(owner), stale...)
+		}
+	}()
+
+	// The bound is inclusive and offset skips the entries already read at
+	// it, since any number of links can share a score
+	bound, offset := "-inf", int64(0)
+	if desc {
+		bound = "+inf"
+	}
+	if afterID != "" {
+		bound = strconv.FormatInt(afterScore, 10)
+	}
+	for {
+		by := &redis.ZRangeBy{Offset: offset, Count: ownedBatch}
+		var entries []redis.Z
+		if desc {
+			by.Max, by.Min = bound, "-inf"
+			entries, err = r.ZRevRangeByScoreWithScores(database.Ctx, key, by).Result()
+		} else {
+			by.Min, by.Max = bound, "+inf"
+			entries, err = r.ZRangeByScoreWithScores(database.Ctx, key, by).Result()
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		// Ties are ordered by ID, the way the cursor was taken
+		ids := make([]string, 0, len(entries))
+		for _, z := range entries {
+			id, _ := z.Member.(string)
+			if afterID != "" && int64(z.Score) == afterScore && (id == afterID || (id > afterID) == desc) {
+				continue
+			}
+			ids = append(ids, id)
+		}
+		pipe := r.Pipeline()
+		cmds := make([]*redis.StringStringMapCmd, len(ids))
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(database.Ctx, id)
+		}
+		if len(ids) > 0 {
+			if _, err := pipe.Exec(database.Ctx); err != nil {
+				return nil, false, err
+			}
+		}
+		for i, cmd := range cmds {
+			fields := cmd.Val()
+			if len(fields) == 0 || fields["owner"] != owner {
+				stale = append(stale, ids[i])
+				continue
+			}
+			link := decode(ids[i], fields)
+			if !keep(link) {
+				continue
+			}
+			if len(page) == limit {
+				return page, true, nil
+			}
+			page = append(page, link)
+		}
+
+		if len(entries) < ownedBatch {
+			return page, false, nil
+		}
+		last := entries[len(entries)-1].Score
+		if next := strconv.FormatInt(int64(last), 10); next != bound {
+			bound, offset = next, 0
+		}
+		for _, z := range entries {
+			if z.Score == last {
+				offset++
+			}
+		}
+	}
+}
+
+// IndexExpiries adds every owned link to its owner's expiry index, for links
+// created before that index existed. It is safe to run more than once.
+func IndexExpiries(r *redis.Client) (int, error) {
+	indexed := 0
+	iter := r.Scan(database.Ctx, 0, "idx:owner:*", 500).Iterator()
+	for iter.Next(database.Ctx) {
+		owner := strings.TrimPrefix(iter.Val(), "idx:owner:")
+		ids, err := r.ZRange(database.Ctx, ownerIndexKey(owner), 0, -1).Result()
+		if err != nil {
+			return indexed, err
+		}
+		for _, id := range ids {
+			link, err := Load(r, id)
+			if err == ErrNotFound || (err == nil && link.Owner != owner) {
+				r.ZRem(database.Ctx, ownerIndexKey(owner), id)
+				continue
+			}
+			if err != nil {
+				return indexed, err
+			}
+			err = r.ZAdd(database.Ctx, ownerExpiryIndexKey(owner), &redis.Z{
+				Score:  float64(ExpiryScore(link)),
+				Member: id,
+			}).Err()
+			if err != nil {
+				return indexed, err
+			}
+			indexed++
+		}
+	}
+	return indexed, iter.Err()
+}
+
+// ByDestination returns the live links pointing at rawURL, however it is
+// spelled.
+func ByDestination(r *redis.Client, rawURL string) ([]*models.Link, error) {
+	key := DestinationKey(rawURL)
+	return fromSet(r, destIndexKey(rawURL), func(link *models.Link) bool {
+		return DestinationKey(link.URL) == key
+	})
+}
+
+// ByHost returns the live links pointing anywhere on host.
+func ByHost(r *redis.Client, host string) ([]*models.Link, error) {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	return fromSet(r, hostIndexKey(host), func(link *models.Link) bool {
+		return Host(link.URL) == host
+	})
+}
+
+// fromSet loads the links listed in an index set, dropping members that are
+// gone or no longer match.
+func fromSet(r *redis.Client, key string, match func(*models.Link) bool) ([]*models.Link, error) {
+	ids, err := r.SMembers(database.Ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.Link, 0, len(ids))
+	var stale []interface{}
+	for _, id := range ids {
+		link, err := Load(r, id)
+		if err == ErrNotFound || (err == nil && !match(link)) {
+			stale = append(stale, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, link)
+	}
+	if len(stale) > 0 {
+		r.SRem(database.Ctx, key, stale...)
+	}
+	return result, nil
+}
+
+// Reindex moves destination and host index entries written under an older
+// key format to their current keys. It is safe to run more than once.
+func Reindex(r *redis.Client) (ReindexReport, error) {
+	var report ReindexReport
+	for _, pattern := range []string{"idx:dest:*", "idx:host:*"} {
+		iter := r.Scan(database.Ctx, 0, pattern, 500).Iterator()
+		for iter.Next(database.Ctx) {
+			key := iter.Val()
+			ids, err := r.SMembers(database.Ctx, key).Result()
+			if err != nil {
+				return report, err
+			}
+			for _, id := range ids {
+				link, err := Load(r, id)
+				if err == ErrNotFound {
+					r.SRem(database.Ctx, key, id)
+					continue
+				}
+				if err != nil {
+					return report, err
+				}
+				want := destIndexKey(link.URL)
+				if pattern == "idx:host:*" {
+					want = hostIndexKey(Host(link.URL))
+				}
+				if want == key {
+					continue
+				}
+				pipe := r.TxPipeline()
+				pipe.SAdd(database.Ctx, want, id)
+				pipe.SRem(database.Ctx, key, id)
+				if _, err := pipe.Exec(database.Ctx); err != nil {
+					return report, err
+				}
+				report.Moved++
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// ReindexReport summarises a Reindex run.
+type ReindexReport struct {
+	Moved int `json:"index_entries_moved"`
+}
@@ -0,0 +1,129 @@
+package links
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/go-redis/redis/v8"
+)
+
+// Before links were hashes, DB 0 held shortID -> url strings (or JSON with
+// the URL under "data" once a tag was added), with the redirect status and
+// owner in a "meta:<shortID>" hash alongside.
+func legacyMetaKey(id string) string {
+	return "meta:" + id
+}
+
+func loadLegacy(r *redis.Client, id string) (*models.Link, error) {
+	val, err := r.Get(database.Ctx, id).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta, err := r.HGetAll(database.Ctx, legacyMetaKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := r.PTTL(database.Ctx, id).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	link := &models.Link{
+		ID:           id,
+		URL:          val,
+		Owner:        meta["owner"],
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Tags:         []string{},
+		RedirectType: atoi(meta["redirect"]),
+	}
+	var data struct {
+		Data string   `json:"data"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(val), &data); err == nil && data.Data != "" {
+		link.URL = data.Data
+		if data.Tags != nil {
+			link.Tags = data.Tags
+		}
+	}
+	if link.RedirectType == 0 {
+		link.RedirectType = http.StatusFound
+	}
+	if ttl > 0 {
+		link.ExpiresAt = now.Add(ttl)
+	}
+	return link, nil
+}
+
+// MigrationReport summarises a MigrateLegacy run.
+type MigrationReport struct {
+	Migrated int `json:"migrated"`
+	Skipped  int `json:"skipped"`
+	Orphans  int `json:"orphans_removed"`
+}
+
+// MigrateLegacy rewrites every string-valued link in DB 0 as a hash record,
+// keeping its remaining TTL, and drops the old meta hashes. Keys that are
+// already hashes are left alone, so it is safe to run more than once.
+func MigrateLegacy(r *redis.Client) (MigrationReport, error) {
+	var report MigrationReport
+	iter := r.Scan(database.Ctx, 0, "*", 500).Iterator()
+	for iter.Next(database.Ctx) {
+		key := iter.Val()
+		if strings.HasPrefix(key, "meta:") {
+			// Metas are consumed with their link; drop the ones whose link is gone.
+			id := strings.TrimPrefix(key, "meta:")
+			if n, err := r.Exists(database.Ctx, id).Result(); err == nil && n == 0 {
+				if err := r.Del(database.Ctx, key).Err(); err != nil {
+					return report, err
+				}
+				report.Orphans++
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "codegen:") {
+			// The code generator's counter, kept here by older versions
+			report.Skipped++
+			continue
+		}
+		kind, err := r.Type(database.Ctx, key).Result()
+		if err != nil {
+			return report, err
+		}
+		if kind != "string" {
+			report.Skipped++
+			continue
+		}
+		err = r.Watch(database.Ctx, func(tx *redis.Tx) error {
+			link, err := loadLegacy(r, key)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(database.Ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(database.Ctx, key, legacyMetaKey(key))
+				Write(pipe, link)
+				return nil
+			})
+			return err
+		}, key, legacyMetaKey(key))
+		switch err {
+		case nil:
+			report.Migrated++
+		case ErrNotFound, redis.TxFailedErr:
+			// Expired or changed underneath us; a re-run picks up anything left.
+			report.Skipped++
+		default:
+			return report, err
+		}
+	}
+	return report, iter.Err()
+}
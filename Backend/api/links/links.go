@@ -0,0 +1,235 @@
+// Package links reads and writes short link records in DB 0.
+//
+// Every link is a Redis hash keyed by its short ID. The hash carries a schema
+// version so the layout can evolve, and the key's TTL mirrors ExpiresAt.
+package links
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const SchemaVersion = 1
+
+// ErrNotFound is returned when no link exists for a short ID.
+var ErrNotFound = errors.New("link not found")
+
+// Load fetches the link stored under id. Records written before links were
+// hashes are still readable, so the server keeps working until the migration
+// has been run.
+func Load(r *redis.Client, id string) (*models.Link, error) {
+	fields, err := r.HGetAll(database.Ctx, id).Result()
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "WRONGTYPE") {
+			return loadLegacy(r, id)
+		}
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+	return decode(id, fields), nil
+}
+
+// Write queues the commands that store link on pipe, including its TTL.
+// Counters such as clicks only ever change through HINCRBY, so they are not
+// written here and a concurrent increment is never lost to a save. The
+// owner and tag indexes are updated alongside.
+func Write(pipe redis.Pipeliner, link *models.Link) {
+	pipe.HSet(database.Ctx, link.ID, encode(link))
+	if link.ExpiresAt.IsZero() {
+		pipe.Persist(database.Ctx, link.ID)
+	} else {
+		pipe.ExpireAt(database.Ctx, link.ID, link.ExpiresAt)
+	}
+	writeIndexes(pipe, link)
+}
+
+// Save stores link in a single transaction. A legacy string record under the
+// same key is replaced, which migrates it on first write.
+func Save(r *redis.Client, link *models.Link) error {
+	kind, err := r.Type(database.Ctx, link.ID).Result()
+	if err != nil {
+		return err
+	}
+	pipe := r.TxPipeline()
+	if kind == "string" {
+		pipe.Del(database.Ctx, link.ID, legacyMetaKey(link.ID))
+	}
+	Write(pipe, link)
+	_, err = pipe.Exec(database.Ctx)
+	return err
+}
+
+// createIfAbsent writes a link hash only if nothing exists under its key yet,
+// so two requests can never claim the same short ID. ARGV[1] is the expiry
+// as a unix timestamp (0 for none), the rest are field/value pairs.
+const createIfAbsent = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], unpack(ARGV, 2))
+if tonumber(ARGV[1]) > 0 then
+	redis.call("EXPIREAT", KEYS[1], ARGV[1])
+end
+return 1`
+
+// ErrTaken is returned by Create when the short ID is already in use.
+var ErrTaken = errors.New("short ID already taken")
+
+// Create atomically claims link.ID and stores the new link with its counters
+// initialised, returning ErrTaken if the ID is in use.
+func Create(r *redis.Client, link *models.Link) error {
+	fields := encode(link)
+	fields["clicks"] = 0
+	if link.MaxClicks > 0 {
+		fields["remaining"] = link.MaxClicks
+	}
+	args := []interface{}{unixOrZero(link.ExpiresAt)}
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	created, err := r.Eval(database.Ctx, createIfAbsent, []string{link.ID}, args...).Int()
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return ErrTaken
+	}
+	pipe := r.Pipeline()
+	writeIndexes(pipe, link)
+	_, err = pipe.Exec(database.Ctx)
+	return err
+}
+
+// consumeClick takes one resolution off a click-limited link. It returns the
+// resolutions left, -1 once the link is used up, or -2 if it has no limit.
+const consumeClick = `
+local left = redis.call("HGET", KEYS[1], "remaining")
+if not left then
+	return -2
+end
+if tonumber(left) <= 0 then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "remaining", -1)`
+
+// ErrExhausted is returned by ConsumeClick when a link has no resolutions left.
+var ErrExhausted = errors.New("link click limit reached")
+
+// ConsumeClick atomically uses up one resolution of link id, so concurrent
+// clicks can never get past its limit. Links without a limit always succeed.
+func ConsumeClick(r *redis.Client, id string) error {
+	left, err := r.Eval(database.Ctx, consumeClick, []string{id}).Int64()
+	if err != nil {
+		return err
+	}
+	if left == -1 {
+		return ErrExhausted
+	}
+	return nil
+}
+
+// incrClicks bumps the click counter only while the link still exists, so a
+// late increment can't resurrect an expired or deleted link as a bare hash.
+const incrClicks = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HINCRBY", KEYS[1], "clicks", ARGV[1])
+end
+return 0`
+
+// AddClicks queues an increment of link id's click counter by n on pipe.
+func AddClicks(pipe redis.Pipeliner, id string, n int64) {
+	pipe.Eval(database.Ctx, incrClicks, []string{id}, n)
+}
+
+func encode(link *models.Link) map[string]interface{} {
+	tags := link.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, _ := json.Marshal(tags)
+	reasonsJSON, _ := json.Marshal(link.RiskReasons)
+	return map[string]interface{}{
+		"v":          SchemaVersion,
+		"url":        link.URL,
+		"owner":      link.Owner,
+		"created_at": link.CreatedAt.Unix(),
+		"updated_at": link.UpdatedAt.Unix(),
+		"expires_at": unixOrZero(link.ExpiresAt),
+		"tags":       string(tagsJSON),
+		"redirect":   link.RedirectType,
+		"password":   link.PasswordHash,
+		"max_clicks": link.MaxClicks,
+		"status":     link.Status,
+		"flag":       link.Flag,
+		"flagged_at": unixOrZero(link.FlaggedAt),
+		"disabled":   link.Disabled,
+		"takedown":   link.TakedownID,
+		"risk":       link.RiskScore,
+		"reasons":    string(reasonsJSON),
+		"lookalike":  link.LookalikeOf,
+	}
+}
+
+func decode(id string, fields map[string]string) *models.Link {
+	link := &models.Link{
+		ID:           id,
+		URL:          fields["url"],
+		Owner:        fields["owner"],
+		CreatedAt:    parseUnix(fields["created_at"]),
+		UpdatedAt:    parseUnix(fields["updated_at"]),
+		ExpiresAt:    parseUnix(fields["expires_at"]),
+		RedirectType: atoi(fields["redirect"]),
+		PasswordHash: fields["password"],
+		Protected:    fields["password"] != "",
+		Status:       fields["status"],
+		Flag:         fields["flag"],
+		FlaggedAt:    parseUnix(fields["flagged_at"]),
+		Disabled:     fields["disabled"],
+		TakedownID:   fields["takedown"],
+		LookalikeOf:  fields["lookalike"],
+		Tags:         []string{},
+	}
+	if link.Status == "" {
+		link.Status = models.LinkActive
+	}
+	link.Clicks, _ = strconv.ParseInt(fields["clicks"], 10, 64)
+	link.MaxClicks, _ = strconv.ParseInt(fields["max_clicks"], 10, 64)
+	link.Remaining, _ = strconv.ParseInt(fields["remaining"], 10, 64)
+	link.RiskScore = atoi(fields["risk"])
+	if t := fields["tags"]; t != "" {
+		_ = json.Unmarshal([]byte(t), &link.Tags)
+	}
+	if r := fields["reasons"]; r != "" {
+		_ = json.Unmarshal([]byte(r), &link.RiskReasons)
+	}
+	return link
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func parseUnix(s string) time.Time {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
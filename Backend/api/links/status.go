@@ -0,0 +1,97 @@
+package links
+
+import (
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/go-redis/redis/v8"
+)
+
+// HeldKey is the sorted set of links awaiting moderation, scored by the time
+// they were held.
+const HeldKey = "moderation:held"
+
+// setIfExists updates fields of a link without recreating it if it has
+// expired or been deleted in the meantime.
+const setIfExists = `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], unpack(ARGV))
+return 1`
+
+func setFields(r *redis.Client, id string, fields ...interface{}) error {
+	return r.Eval(database.Ctx, setIfExists, []string{id}, fields...).Err()
+}
+
+// SetFlag records why a link's destination is considered unsafe.
+func SetFlag(r *redis.Client, id, flag string) error {
+	return setFields(r, id, "flag", flag, "flagged_at", time.Now().Unix())
+}
+
+// Held lists the links awaiting moderation, oldest first. Links that have
+// expired since are dropped from the queue.
+func Held(r *redis.Client) ([]*models.Link, error) {
+	ids, err := r.ZRange(database.Ctx, HeldKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	held := make([]*models.Link, 0, len(ids))
+	for _, id := range ids {
+		link, err := Load(r, id)
+		if err == ErrNotFound || (err == nil && link.Status != models.LinkHeld) {
+			r.ZRem(database.Ctx, HeldKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		held = append(held, link)
+	}
+	return held, nil
+}
+
+// Release makes a held link resolvable.
+func Release(r *redis.Client, id string) error {
+	if err := setFields(r, id, "status", models.LinkActive); err != nil {
+		return err
+	}
+	return r.ZRem(database.Ctx, HeldKey, id).Err()
+}
+
+// Disable takes a link down on behalf of takedown, remembering its status so
+// the takedown can be undone.
+func Disable(r *redis.Client, id, takedown, reason string) error {
+	return r.Eval(database.Ctx, disable, []string{id}, takedown, reason).Err()
+}
+
+const disable = `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local status = redis.call("HGET", KEYS[1], "status")
+if status ~= "disabled" then
+	redis.call("HSET", KEYS[1], "status_before", status or "active")
+end
+redis.call("HSET", KEYS[1], "status", "disabled", "takedown", ARGV[1], "disabled", ARGV[2])
+return 1`
+
+// Restore undoes takedown for one link. Links disabled by a different
+// takedown since are left alone; the result reports whether it was restored.
+func Restore(r *redis.Client, id, takedown string) (bool, error) {
+	n, err := r.Eval(database.Ctx, restore, []string{id}, takedown).Int()
+	return n == 1, err
+}
+
+const restore = `
+if redis.call("HGET", KEYS[1], "takedown") ~= ARGV[1] then
+	return 0
+end
+local before = redis.call("HGET", KEYS[1], "status_before")
+if not before or before == "" then
+	before = "active"
+end
+redis.call("HSET", KEYS[1], "status", before, "takedown", "", "disabled", "")
+redis.call("HDEL", KEYS[1], "status_before")
+return 1`
@@ -19,37 +19,61 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Expected format: "Bearer <token>"
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
+		email, errMsg := emailFromHeader(authHeader)
+		if errMsg != "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errMsg})
 			return
 		}
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+		c.Set("userEmail", email) // Now you can get this in handlers
+
+		c.Next()
+	}
+}
+
+// OptionalJWTAuthMiddleware sets userEmail when the request carries a valid
+// token and lets anonymous requests through untouched.
+func OptionalJWTAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			if email, errMsg := emailFromHeader(authHeader); errMsg == "" {
+				c.Set("userEmail", email)
 			}
-			return jwtSecret, nil
-		})
-		
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			return
 		}
+		c.Next()
+	}
+}
 
-		// Token is valid, extract email
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			return
+// emailFromHeader validates a "Bearer <token>" header and returns the email
+// claim, or a client-facing error message.
+func emailFromHeader(authHeader string) (string, string) {
+	// Expected format: "Bearer <token>"
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return "", "Invalid Authorization header format"
+	}
+
+	// Parse and validate token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
 		}
+		return jwtSecret, nil
+	})
 
-		email := claims["email"].(string)
-		c.Set("userEmail", email) // Now you can get this in handlers
+	if err != nil || !token.Valid {
+		return "", "Invalid or expired token"
+	}
 
-		c.Next()
+	// Token is valid, extract email
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "Invalid token claims"
+	}
+	email, ok := claims["email"].(string)
+	if !ok || email == "" {
+		return "", "Invalid token claims"
 	}
+	return email, ""
 }
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly rejects callers who aren't registered admins. It must run after
+// JWTAuthMiddleware so userEmail is set.
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.IsAdmin(c.GetString("userEmail")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
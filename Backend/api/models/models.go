@@ -3,9 +3,14 @@ package models
 import "time"
 
 type Request struct {
-	URL         string        `json:"url"`
-	CustomShort string        `json:"short"`
-	Expiry      time.Duration `json:"expiry"`
+	URL          string        `json:"url"`
+	CustomShort  string        `json:"short"`
+	Expiry       time.Duration `json:"expiry"`
+	RedirectType int           `json:"redirect_type"` // 301, 302, 307 or 308; defaults to 302
+	Tags         []string      `json:"tags"`
+	Password     string        `json:"password"` // optional; visitors must enter it before being redirected
+	MaxClicks    int64         `json:"max_clicks"` // optional; the link stops working after this many resolutions
+	OneTime      bool          `json:"one_time"`   // shorthand for max_clicks = 1
 }
 
 type Responce struct {
@@ -14,18 +19,56 @@ type Responce struct {
 	Expiry          time.Duration `json:"expiry"`
 	XRateRemaining  int           `json:"rate_limit"`
 	XRateLimitReset time.Duration `json:"rate_limit_reset"`
+	Status          string        `json:"status,omitempty"` // "held" when the link awaits moderation
+	RiskScore       int           `json:"risk_score"`       // how suspicious the destination looks, 0 to 100
+	RiskReasons     []string      `json:"risk_reasons,omitempty"`
+	LookalikeOf     string        `json:"lookalike_of,omitempty"` // protected brand domain the destination imitates
 }
 
+// Link is the record stored for every short link in DB 0.
+type Link struct {
+	ID           string    `json:"id"`
+	URL          string    `json:"url"`
+	Owner        string    `json:"owner,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"` // zero means the link never expires
+	Tags         []string  `json:"tags"`
+	RedirectType int       `json:"redirect_type"`
+	Clicks       int64     `json:"clicks"`
+	PasswordHash string    `json:"-"` // bcrypt hash, empty when the link isn't protected
+	Protected    bool      `json:"protected"`
+	MaxClicks    int64     `json:"max_clicks,omitempty"`       // 0 means unlimited
+	Remaining    int64     `json:"remaining_clicks,omitempty"` // resolutions left when MaxClicks is set
+	Status       string    `json:"status"`                     // active, held while awaiting moderation, or disabled
+	Flag         string    `json:"flag,omitempty"`             // why the destination was flagged, e.g. verified_scam
+	FlaggedAt    time.Time `json:"flagged_at,omitzero"`
+	Disabled     string    `json:"disabled_reason,omitempty"` // why an admin takedown disabled the link
+	TakedownID   string    `json:"takedown_id,omitempty"`
+	RiskScore    int       `json:"risk_score"` // lexical score of the destination when the link was created
+	RiskReasons  []string  `json:"risk_reasons,omitempty"`
+	LookalikeOf  string    `json:"lookalike_of,omitempty"` // protected brand domain the destination imitates
+}
+
+// Link statuses.
+const (
+	LinkActive   = "active"
+	LinkHeld     = "held"
+	LinkDisabled = "disabled"
+)
+
 type Scam struct {
 	URL         string `json:"url"`
 	Description string `json:"description"`
-	Rating      int    `json:"rating"` // Number of people who have reported it as a scam
+	Category    string `json:"category"` // phishing, fraud, malware, impersonation, spam or other
+	Source      string `json:"source"`   // optional: where the reporter came across it
+	Rating      int    `json:"rating"`   // Number of people who have reported it as a scam
 }
 
 type Admin struct {
 	Name         string   `json:"name" binding:"required"`
 	Email        string   `json:"email" binding:"required"`
-	VerifiedURLs []string `json:"verified_urls"`
+	VerifiedURLs []string `json:"verified_urls"` // Legacy: verifications now live in the verified index (go run . migrate verified)
 }
 
 type User struct {
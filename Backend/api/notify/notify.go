@@ -0,0 +1,60 @@
+// Package notify keeps a short inbox of notifications per user in DB 4,
+// next to the user accounts.
+package notify
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+)
+
+const maxPerUser = 100
+
+// Notification is one message for a user.
+type Notification struct {
+	At      time.Time         `json:"at"`
+	Type    string            `json:"type"`
+	Message string            `json:"message"`
+	Data    map[string]string `json:"data,omitempty"`
+}
+
+func inboxKey(email string) string {
+	return "notifications:" + email
+}
+
+// Push adds n to email's inbox, keeping only the newest maxPerUser.
+func Push(email string, n Notification) error {
+	if n.At.IsZero() {
+		n.At = time.Now().UTC()
+	}
+	raw, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	r := database.CreateClient(4)
+	defer r.Close()
+	pipe := r.TxPipeline()
+	pipe.LPush(database.Ctx, inboxKey(email), raw)
+	pipe.LTrim(database.Ctx, inboxKey(email), 0, maxPerUser-1)
+	_, err = pipe.Exec(database.Ctx)
+	return err
+}
+
+// List returns email's notifications, newest first.
+func List(email string) ([]Notification, error) {
+	r := database.CreateClient(4)
+	defer r.Close()
+	vals, err := r.LRange(database.Ctx, inboxKey(email), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Notification, 0, len(vals))
+	for _, val := range vals {
+		var n Notification
+		if json.Unmarshal([]byte(val), &n) == nil {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
@@ -1,10 +1,13 @@
 package Scam
 
 import (
-	"encoding/json"
+	"log"
 
+	"github.com/abdulhameedsk/URL-Shortner/api/brands"
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
 	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,42 +18,45 @@ func AddScam(c *gin.Context) {
 		return
 	}
 
-	r3 := database.CreateClient(2)
-	defer r3.Close()
-
-	// Check if scam info already exists for this URL
-	val, err := r3.Get(database.Ctx, scam.URL).Result()
-	var data map[string]interface{}
-
-	if err == nil {
-		// If it exists, unmarshal existing JSON
-		if err := json.Unmarshal([]byte(val), &data); err != nil {
-			c.JSON(500, gin.H{"error": "Failed to parse existing scam data"})
-			return
-		}
-	} else {
-		// If it doesn't exist, start fresh
-		data = make(map[string]interface{})
+	if _, err := canonical.URL(scam.URL); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid URL"})
+		return
 	}
 
-	// Add/update description and increment rating
-	data["description"] = scam.Description
-	if rating, ok := data["rating"].(float64); ok {
-		data["rating"] = rating + 1
-	} else {
-		data["rating"] = 1
-	}
+	r3 := database.CreateClient(2)
+	defer r3.Close()
 
-	updatedData, err := json.Marshal(data)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal updated data"})
+	// Every report is kept in the URL's history; the summary record keyed by
+	// its canonical URL carries the counts and the latest description
+	data, err := scams.AddReport(r3, scam.URL, scams.Report{
+		Reporter:    c.GetString("userEmail"),
+		Description: scam.Description,
+		Category:    scam.Category,
+		Source:      scam.Source,
+	})
+	switch err {
+	case nil:
+	case scams.ErrCategory, scams.ErrReportLimit:
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
-	}
-
-	if err := r3.Set(database.Ctx, scam.URL, updatedData, 0).Err(); err != nil {
+	case scams.ErrCooldown, scams.ErrReported:
+		c.JSON(409, gin.H{"error": err.Error()})
+		return
+	default:
 		c.JSON(500, gin.H{"error": "Failed to save scam data"})
 		return
 	}
 
+	// Lookalikes of protected brands are flagged with the brand they imitate
+	if set, err := brands.Load(); err != nil {
+		log.Println("brand list lookup failed:", err)
+	} else if match := set.Match(scam.URL); match != nil {
+		if flagged, err := scams.FlagLookalike(r3, scam.URL, match.Brand, match.Method); err != nil {
+			log.Println("flagging lookalike", scam.URL, "failed:", err)
+		} else {
+			data = flagged
+		}
+	}
+
 	c.JSON(200, gin.H{"message": "Scam added successfully", "data": data})
 }
@@ -1,14 +1,14 @@
 package Scam
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
-	"github.com/abdulhameedsk/URL-Shortner/api/database"
 	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// AddAdmin registers another admin. Only admins may call it; the first one
+// is created from the command line (go run . admin add <email> <name>).
 func AddAdmin(c *gin.Context) {
 	var admin models.Admin
 
@@ -17,28 +17,16 @@ func AddAdmin(c *gin.Context) {
 		return
 	}
 
-	r3 := database.CreateClient(3)
-	defer r3.Close()
-
-	adminData, err := json.Marshal(admin)
+	// Prevent duplicate admin
+	added, err := utils.CreateAdmin(admin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize admin data"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store admin data"})
 		return
 	}
-
-	key := fmt.Sprintf("admin:%s", admin.Email)
-
-	// Prevent duplicate admin
-	exists, _ := r3.Exists(database.Ctx, key).Result()
-	if exists == 1 {
+	if !added {
 		c.JSON(http.StatusConflict, gin.H{"error": "Admin already exists"})
 		return
 	}
 
-	if err := r3.Set(database.Ctx, key, adminData, 0).Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store admin data"})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{"message": "Admin added successfully", "data": admin})
 }
@@ -3,6 +3,7 @@ import (
 	"encoding/json"
 
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
 	"github.com/gin-gonic/gin"
 )
 func GetScams(c *gin.Context) {
@@ -23,6 +24,9 @@ func GetScams(c *gin.Context) {
 
 	var scamdetails []map[string]interface{}
 	for _, key := range keys {
+		if !scams.IsReportKey(key) {
+			continue
+		}
 		val, err := r3.Get(database.Ctx, key).Result()
 		if err != nil {
 			continue 
@@ -31,7 +35,10 @@ func GetScams(c *gin.Context) {
 		if err := json.Unmarshal([]byte(val), &scam); err != nil {
 			continue
 		}
-		scam["url"] = key 
+		// Records carry the canonical URL; older ones only have their key
+		if _, ok := scam["url"].(string); !ok {
+			scam["url"] = key
+		}
 		scamdetails = append(scamdetails, scam)
 	}
 
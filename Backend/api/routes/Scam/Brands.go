@@ -0,0 +1,63 @@
+package Scam
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/brands"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/gin-gonic/gin"
+)
+
+// GetBrands lists the protected brands.
+func GetBrands(c *gin.Context) {
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	list, err := brands.All(r3)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load brands"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"brands": list})
+}
+
+// AddBrand protects a domain. Shortened links and scam reports whose host
+// imitates it are flagged with it from then on.
+func AddBrand(c *gin.Context) {
+	var body struct {
+		Domain string `json:"domain" binding:"required"`
+		Name   string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	brand, err := brands.New(body.Domain, body.Name, c.GetString("userEmail"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	if err := brands.Add(r3, brand); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save brand"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Brand protected", "brand": brand})
+}
+
+// RemoveBrand stops protecting a domain. Links and reports already flagged
+// keep their flag.
+func RemoveBrand(c *gin.Context) {
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	removed, err := brands.Remove(r3, c.Param("domain"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove brand"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Brand not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Brand removed", "domain": c.Param("domain")})
+}
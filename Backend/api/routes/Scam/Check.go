@@ -0,0 +1,57 @@
+package Scam
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/safety"
+	"github.com/gin-gonic/gin"
+)
+
+const maxCheckURLs = 500
+
+// checkResult is the safety verdict for one URL.
+type checkResult struct {
+	URL string `json:"url"`
+	safety.Verdict
+}
+
+// CheckURL answers whether ?url= is a known scam.
+func CheckURL(c *gin.Context) {
+	url := c.Query("url")
+	if url == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+	verdict, err := safety.Check(url)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check URL"})
+		return
+	}
+	c.JSON(http.StatusOK, checkResult{URL: url, Verdict: verdict})
+}
+
+// CheckURLs is CheckURL for up to maxCheckURLs URLs, given as {"urls": [...]}.
+// Results are in request order.
+func CheckURLs(c *gin.Context) {
+	var body struct {
+		URLs []string `json:"urls" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(body.URLs) == 0 || len(body.URLs) > maxCheckURLs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "urls must hold between 1 and 500 URLs"})
+		return
+	}
+	verdicts, err := safety.CheckAll(body.URLs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check URLs"})
+		return
+	}
+	results := make([]checkResult, len(body.URLs))
+	for i, url := range body.URLs {
+		results[i] = checkResult{URL: url, Verdict: verdicts[i]}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,68 @@
+package Scam
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/feed"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/abdulhameedsk/URL-Shortner/api/verified"
+	"github.com/gin-gonic/gin"
+)
+
+// GetFeed serves the verified scams as a blocklist in the format named in
+// the path: hosts, domains, csv, adblock, rpz or stix. The ETag and
+// Last-Modified headers change only when the list does, so pollers can send
+// If-None-Match or If-Modified-Since and get a 304.
+func GetFeed(c *gin.Context) {
+	format := c.Param("format")
+	if feed.ContentType(format) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": feed.ErrFormat.Error()})
+		return
+	}
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+
+	version, err := verified.Version(r3)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+	verifiedAt, err := verified.Updated(r3)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+	entriesAt, err := scamrules.Updated(r3)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+	updated := verifiedAt
+	if entriesAt.After(updated) {
+		updated = entriesAt
+	}
+
+	etag := feed.ETag(format, version, entriesAt)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if !updated.IsZero() {
+		c.Header("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	}
+	if feed.NotModified(c.GetHeader("If-None-Match"), c.GetHeader("If-Modified-Since"), etag, updated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	entries, err := scamrules.All(r3)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+	body, err := feed.Render(format, entries, updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render feed"})
+		return
+	}
+	c.Data(http.StatusOK, feed.ContentType(format), body)
+}
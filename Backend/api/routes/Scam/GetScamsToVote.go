@@ -1,50 +1,41 @@
 package Scam
 import (
-	"encoding/json"
-
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
 	"github.com/gin-gonic/gin"
 )
 
-//This is used to increse scam votes for URLs uses Database 3
+//This is used to vote on reported scam URLs, uses Database 2
+//Each user has one vote per URL: up (it is a scam), down (it is legitimate)
+//or retract to withdraw it. Voting again replaces the earlier vote.
 
 func Vote(c *gin.Context) {
 	r3 := database.CreateClient(2)
 	defer r3.Close()
 	var body struct {
-		URL string `json:"url"`
+		URL  string `json:"url"`
+		Vote string `json:"vote"` // up (default), down or retract
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(400, gin.H{"error": "Invalid request body"})
 		return
 	}
-	val, err := r3.Get(database.Ctx, body.URL).Result()
-	if err != nil {
-		c.JSON(404, gin.H{"error": "URL not found or no scam data available"})
-		return
+	if body.Vote == "" {
+		body.Vote = scams.VoteUp
 	}
-	var data map[string]interface{}
-	if err := json.Unmarshal([]byte(val), &data); err != nil {
-		c.JSON(500, gin.H{"error": "Failed to parse scam data"})
+	tally, err := scams.CastVote(r3, body.URL, c.GetString("userEmail"), body.Vote)
+	switch err {
+	case nil:
+	case scams.ErrVote:
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
-	}
-	//If no error improve rating for that URL
-	if rating, ok := data["rating"].(float64); ok {
-		data["rating"] = rating + 1
-	} else {
-		data["rating"] = 1
-	}
-
-	updatedData, err := json.Marshal(data)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal updated data"})
+	case scams.ErrNoReport:
+		c.JSON(404, gin.H{"error": err.Error()})
 		return
-	}
-
-	if err := r3.Set(database.Ctx, body.URL, updatedData, 0).Err(); err != nil {
+	default:
 		c.JSON(500, gin.H{"error": "Failed to save updated scam data"})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "Scam vote updated successfully", "data": data})
+	c.JSON(200, gin.H{"message": "Scam vote updated successfully", "data": tally})
 }
@@ -0,0 +1,57 @@
+package Scam
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/hashlist"
+	"github.com/gin-gonic/gin"
+)
+
+const maxFullHashPrefixes = 20
+
+// GetHashPrefixes brings a client's list of 4-byte hash prefixes up to date.
+// ?version= is the token from its last update; without one, or with one too
+// old to diff from, the whole list is sent with reset set.
+func GetHashPrefixes(c *gin.Context) {
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	update, err := hashlist.Updates(r3, c.Query("version"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load hash prefixes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"version":             update.Version,
+		"reset":               update.Reset,
+		"additions":           update.Additions,
+		"removals":            update.Removals,
+		"next_update_seconds": int(hashlist.RefreshInterval().Seconds()),
+	})
+}
+
+// GetFullHashes returns the full SHA-256 hashes listed under each ?prefix=
+// (8 hex characters, up to 20 of them). A client compares them with the
+// hashes of the URL it is checking, which it never sends.
+func GetFullHashes(c *gin.Context) {
+	prefixes := c.QueryArray("prefix")
+	if len(prefixes) == 0 || len(prefixes) > maxFullHashPrefixes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "give between 1 and 20 prefix parameters"})
+		return
+	}
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	matches, err := hashlist.FullHashes(r3, prefixes)
+	if err == hashlist.ErrPrefix {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load full hashes"})
+		return
+	}
+	seconds := int(hashlist.RefreshInterval().Seconds())
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", seconds))
+	c.JSON(http.StatusOK, gin.H{"matches": matches, "cache_seconds": seconds})
+}
@@ -0,0 +1,62 @@
+package Scam
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/importer"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportFeed imports a phishing feed file into the scam reports. The file is
+// named relative to IMPORT_DIR and can't be outside it; imports are off
+// while IMPORT_DIR is unset.
+func ImportFeed(c *gin.Context) {
+	var body struct {
+		Source string `json:"source" binding:"required"`
+		File   string `json:"file" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	dir := os.Getenv("IMPORT_DIR")
+	if dir == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Imports are disabled; set IMPORT_DIR"})
+		return
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open import directory"})
+		return
+	}
+	defer root.Close()
+	// Root refuses names that lead outside dir, symlinks included
+	f, err := root.Open(body.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be inside the import directory"})
+		return
+	}
+	defer f.Close()
+
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	result, err := importer.Import(r2, body.Source, f)
+	switch {
+	case err == importer.ErrSource:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, importer.ErrFormat):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "result": result})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed", "result": result})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Import finished", "result": result})
+	}
+}
@@ -0,0 +1,121 @@
+package Scam
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/abdulhameedsk/URL-Shortner/api/takedown"
+	"github.com/gin-gonic/gin"
+)
+
+// reviewRequest is the body of the review decision endpoints.
+type reviewRequest struct {
+	URL    string `json:"url" binding:"required"`
+	Reason string `json:"reason"`
+	Scope  string `json:"scope"` // verify only: "domain" takes down links to the whole host
+}
+
+// GetReviewQueue lists reports waiting for review, highest rated first.
+// Query parameters: offset and limit.
+func GetReviewQueue(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	entries, total, err := scams.Queue(r2, offset, min(limit, 100))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load review queue"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": entries, "total": total})
+}
+
+// ClaimReport puts a report under review by the calling admin.
+func ClaimReport(c *gin.Context) {
+	var body reviewRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	data, err := scams.Claim(r2, body.URL, c.GetString("userEmail"))
+	if !reviewOK(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Report claimed", "data": data})
+}
+
+// VerifyReport verifies a report: the URL goes in the verified index,
+// attributed to the calling admin, and existing links to it are taken down.
+func VerifyReport(c *gin.Context) {
+	var body reviewRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	url, err := canonical.URL(body.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL"})
+		return
+	}
+	admin := c.GetString("userEmail")
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	data, err := scams.Verify(r2, url, admin, body.Reason)
+	if !reviewOK(c, err) {
+		return
+	}
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	if _, added, err := verifyURL(r3, admin, url, body.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save updated admin data"})
+		return
+	} else if added {
+		takedown.Start(url, body.Scope, admin, body.Reason)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Report verified", "data": data})
+}
+
+// RejectReport rejects a report; the URL can't be reported again until the
+// rejection cooldown passes.
+func RejectReport(c *gin.Context) {
+	var body reviewRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	data, err := scams.Reject(r2, body.URL, c.GetString("userEmail"), body.Reason)
+	if !reviewOK(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Report rejected", "data": data})
+}
+
+// reviewOK writes the response for a failed review action.
+func reviewOK(c *gin.Context, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case scams.ErrNoReport:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case scams.ErrClaimed, scams.ErrResolved:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report"})
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package Scam
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/abdulhameedsk/URL-Shortner/api/verified"
+	"github.com/gin-gonic/gin"
+)
+
+// RevokeVerification withdraws a URL's verification and puts its report, if
+// it has one, back in the review queue. Links already taken down stay down
+// until their takedown is undone.
+func RevokeVerification(c *gin.Context) {
+	var body struct {
+		URL    string `json:"url" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url and reason are required"})
+		return
+	}
+	admin := c.GetString("userEmail")
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	revoked, err := verified.Revoke(r3, body.URL, admin, body.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke verification"})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL is not verified"})
+		return
+	}
+	scamrules.Invalidate()
+
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	if _, err := scams.Reopen(r2, body.URL, admin, body.Reason); err != nil && err != scams.ErrNoReport {
+		log.Println("failed to reopen report of", body.URL+":", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Verification revoked"})
+}
+
+// GetVerificationAudit returns verifications and revocations, newest first.
+// Query parameters: offset and limit.
+func GetVerificationAudit(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	events, total, err := verified.Audit(r3, offset, min(limit, 200))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load audit trail"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": total})
+}
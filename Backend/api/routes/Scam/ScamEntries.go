@@ -0,0 +1,63 @@
+package Scam
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/gin-gonic/gin"
+)
+
+// GetScamEntries lists every scam entry, verified URLs included.
+func GetScamEntries(c *gin.Context) {
+	set, err := scamrules.Load()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scam entries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": set.Entries()})
+}
+
+// AddScamEntry registers a domain, path-prefix, pattern or URL entry.
+func AddScamEntry(c *gin.Context) {
+	var body struct {
+		Scope  string `json:"scope" binding:"required"`
+		Value  string `json:"value" binding:"required"`
+		Kind   string `json:"kind"` // glob (default) or regex, for patterns
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	entry, err := scamrules.New(body.Scope, body.Value, body.Kind, c.GetString("userEmail"), body.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	if err := scamrules.Add(r3, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scam entry"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Scam entry added", "entry": entry})
+}
+
+// RemoveScamEntry deletes an entry added through AddScamEntry. Verified URLs
+// live in the verified index and are withdrawn with RevokeVerification
+// (POST /api/v1/admin/verified/revoke) instead.
+func RemoveScamEntry(c *gin.Context) {
+	r3 := database.CreateClient(3)
+	defer r3.Close()
+	removed, err := scamrules.Remove(r3, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove scam entry"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scam entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Scam entry removed"})
+}
@@ -0,0 +1,54 @@
+package Scam
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/abdulhameedsk/URL-Shortner/api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetScamReports returns the report timeline for ?url=, newest first, paged
+// with offset and limit. Reporters are only shown to admins and to the
+// reporter themselves.
+func GetScamReports(c *gin.Context) {
+	url := c.Query("url")
+	if url == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	limit = min(limit, 100)
+
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	reports, total, err := scams.Timeline(r2, url, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reports"})
+		return
+	}
+	email := c.GetString("userEmail")
+	if email == "" || !utils.IsAdmin(email) {
+		for i := range reports {
+			if reports[i].Reporter != email {
+				reports[i].Reporter = ""
+			}
+		}
+	}
+	resp := gin.H{"url": url, "reports": reports, "total": total, "next_offset": nil}
+	if offset+int64(len(reports)) < total {
+		resp["next_offset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, resp)
+}
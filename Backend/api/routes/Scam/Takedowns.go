@@ -0,0 +1,66 @@
+package Scam
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/takedown"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// GetTakedowns lists takedowns newest first. Query parameters: offset and limit.
+func GetTakedowns(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	r := database.CreateClient(0)
+	defer r.Close()
+	records, err := takedown.List(r, offset, min(limit, 100))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load takedowns"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"takedowns": records})
+}
+
+// GetTakedown returns one takedown with the links it disabled.
+func GetTakedown(c *gin.Context) {
+	r := database.CreateClient(0)
+	defer r.Close()
+	record, err := takedown.Get(r, c.Param("id"))
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Takedown not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load takedown"})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// UndoTakedown restores the links a takedown disabled, for when the
+// verification behind it was a mistake.
+func UndoTakedown(c *gin.Context) {
+	r := database.CreateClient(0)
+	defer r.Close()
+	restored, err := takedown.Undo(r, c.Param("id"), c.GetString("userEmail"))
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Takedown not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo takedown"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Takedown undone", "restored": restored})
+}
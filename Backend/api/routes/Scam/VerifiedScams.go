@@ -1,43 +1,60 @@
 package Scam
 import (
-	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
-	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/abdulhameedsk/URL-Shortner/api/verified"
 	"github.com/gin-gonic/gin"
 )
 
-// This is used to get verified scams from Database 3
-// Go to DB3 and retrieve all urls from admins data
+// This is used to get verified scams from the verified index in Database 3,
+// a page at a time (offset, limit), plus the domain, prefix and pattern
+// entries admins registered. With ?url= it instead returns the entries
+// covering that URL, most specific first.
 func GetVerifiedScams(c *gin.Context) {
+	if url := c.Query("url"); url != "" {
+		set, err := scamrules.Load()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot load verified scams"})
+			return
+		}
+		matches := set.Match(url)
+		c.JSON(http.StatusOK, gin.H{"url": url, "verified": len(matches) > 0, "matches": matches})
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	limit = min(limit, 1000)
+
 	r3 := database.CreateClient(3)
 	defer r3.Close()
-	//Go into r3 and get all URLs from admins data
-	keys, err := r3.Keys(database.Ctx, "admin:*").Result()
+	records, total, err := verified.Page(r3, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Cannot find admins",
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot load verified scams"})
+		return
 	}
-	//Go does not have builtin Set Data Structure
-	UniqueScamUrls := make(map[string]bool)
-	for _, key := range keys {
-		val, err := r3.Get(database.Ctx, key).Result()
-		if err != nil {
-			continue
-		}
-		var admin models.Admin
-		if err := json.Unmarshal([]byte(val), &admin); err != nil {
-			continue
-		}
-		for _, url := range admin.VerifiedURLs {
-			UniqueScamUrls[url] = true
-		}
+	entries, err := scamrules.Scoped(r3)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot load scam entries"})
+		return
+	}
+	scams := make([]string, len(records))
+	for i, record := range records {
+		scams[i] = record.URL
 	}
-	var scams []string
-	for url := range UniqueScamUrls {
-		scams = append(scams, url)
+	resp := gin.H{"verified_scams": scams, "verified": records, "entries": entries, "total": total, "next_offset": nil}
+	if offset+int64(len(records)) < total {
+		resp["next_offset"] = offset + limit
 	}
-	c.JSON(http.StatusOK, gin.H{"verified_scams": scams})
+	c.JSON(http.StatusOK, resp)
 }
@@ -1,21 +1,32 @@
 package Scam
 
 import (
-	"encoding/json"
+	"log"
 	"net/http"
 
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
-	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/abdulhameedsk/URL-Shortner/api/takedown"
+	"github.com/abdulhameedsk/URL-Shortner/api/verified"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 )
 
+// VerifyScamByAdmin adds a URL to the verified index on behalf of the
+// calling admin and takes down existing links to it. It runs behind
+// AdminOnly; the verifier is always the JWT caller.
 func VerifyScamByAdmin(c *gin.Context) {
 	r3 := database.CreateClient(3)
 	defer r3.Close()
 
 	var body struct {
-		Email string `json:"email"` // admin email
-		URL   string `json:"url"`   // scam to verify
+		URL string `json:"url"` // scam to verify
+		// Existing links to the URL are disabled once it's verified; scope
+		// "domain" widens that to every link on its host
+		Scope  string `json:"scope"`
+		Reason string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -23,40 +34,46 @@ func VerifyScamByAdmin(c *gin.Context) {
 		return
 	}
 
-	key := "admin:" + body.Email
-	val, err := r3.Get(database.Ctx, key).Result()
+	url, err := canonical.URL(body.URL)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL"})
 		return
 	}
 
-	var admin models.Admin
-	if err := json.Unmarshal([]byte(val), &admin); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse admin data"})
+	admin := c.GetString("userEmail")
+	record, added, err := verifyURL(r3, admin, url, body.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save verification"})
 		return
 	}
-
-	// Check if URL is already verified
-	for _, url := range admin.VerifiedURLs {
-		if url == body.URL {
-			c.JSON(http.StatusOK, gin.H{"message": "URL already verified by admin"})
-			return
-		}
+	if !added {
+		c.JSON(http.StatusOK, gin.H{"message": "URL already verified by admin", "verified": record})
+		return
 	}
+	markVerified(url, admin, body.Reason)
 
-	// Add URL to verified list
-	admin.VerifiedURLs = append(admin.VerifiedURLs, body.URL)
+	takedown.Start(url, body.Scope, admin, body.Reason)
+	c.JSON(http.StatusOK, gin.H{"message": "URL verified successfully", "verified": record})
+}
 
-	adminData, err := json.Marshal(admin)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update admin data"})
-		return
+// verifyURL adds url to the verified index on behalf of admin. It returns
+// the URL's record and whether it was added, false when it was already
+// verified.
+func verifyURL(r3 *redis.Client, admin, url, reason string) (*verified.Record, bool, error) {
+	record, added, err := verified.Add(r3, url, admin, reason)
+	if err == nil && added {
+		scamrules.Invalidate()
 	}
+	return record, added, err
+}
 
-	if err := r3.Set(database.Ctx, key, adminData, 0).Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save updated admin data"})
-		return
+// markVerified records the decision on url's community report, if it has
+// one, so it leaves the review queue.
+func markVerified(url, admin, reason string) {
+	r2 := database.CreateClient(2)
+	defer r2.Close()
+	_, err := scams.MarkVerified(r2, url, admin, reason)
+	if err != nil && err != scams.ErrNoReport {
+		log.Println("failed to mark report of", url, "verified:", err)
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "URL verified successfully", "verified_urls": admin.VerifiedURLs})
 }
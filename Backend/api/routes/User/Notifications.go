@@ -0,0 +1,18 @@
+package User
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/notify"
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications returns the JWT user's notifications, newest first.
+func GetNotifications(c *gin.Context) {
+	notifications, err := notify.List(c.GetString("userEmail"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications})
+}
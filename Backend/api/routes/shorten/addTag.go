@@ -1,11 +1,11 @@
 package shorten
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,46 +25,25 @@ func AddTag(c *gin.Context) {
 	tag := tagRequest.Tag
 	r := database.CreateClient(0)
 	defer r.Close()
-	val, err := r.Get(database.Ctx, shortId).Result()
-	if err != nil {
-		fmt.Println("🔴 Redis error:", err)
-		fmt.Println("🔎 shortId received:", shortId)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Short ID not found"})
+	link, ok := loadLink(c, r, shortId)
+	if !ok {
 		return
 	}
-	var data map[string]interface{}
-	if err := json.Unmarshal([]byte(val), &data); err != nil {
-		//If data is not valid JSON we assume it is a string
-		data = make(map[string]interface{})
-		data["data"] = val
-	}
-	//check if tags already exist and if its slice of strings
-	var tags []string
-	if existingTags, ok := data["tags"].([]interface{}); ok {
-		for _, t := range existingTags {
-			if tagStr, ok := t.(string); ok {
-				tags = append(tags, tagStr)
-			}
-		}
+	if !canModify(c, link) {
+		return
 	}
 	//check for duplicate tag
-	for _, existingTag := range tags {
+	for _, existingTag := range link.Tags {
 		if existingTag == tag {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag already exists"})
 			return
 		}
 	}
 	//Add the new tag
-	tags = append(tags, tag)
-	data["tags"] = tags
-	// Save the updated data back to the database
-	updatedData, err := json.Marshal(data)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
-		return
-	}
-	// Save the updated data back to the database
-	if err := r.Set(database.Ctx, shortId, updatedData, 0).Err(); err != nil {
+	link.Tags = append(link.Tags, tag)
+	link.UpdatedAt = time.Now().UTC()
+	// Save the updated record back to the database, keeping its expiry
+	if err := links.Save(r, link); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
 		return
 	}
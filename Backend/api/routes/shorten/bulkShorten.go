@@ -0,0 +1,204 @@
+package shorten
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkRows = 1000
+
+// maxBulkPasswordRows caps password-protected rows per request; each one is
+// bcrypt-hashed, which costs far more CPU than the rest of a row.
+const maxBulkPasswordRows = 50
+
+// bulkRow is one row of a bulk request. ParseErr is set when the row could
+// not be read, in which case it is reported without being shortened.
+type bulkRow struct {
+	Request  models.Request
+	ParseErr string
+}
+
+type bulkResult struct {
+	Row       int    `json:"row"`
+	URL       string `json:"url"`
+	Short     string `json:"short,omitempty"`
+	Status    string `json:"status,omitempty"`
+	RiskScore int    `json:"risk_score,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// BulkShortenURL shortens many URLs in one request. It accepts either a JSON
+// array of shorten requests or a multipart upload with a CSV "file" of
+// url,short,expiry,tags,password,max_clicks rows (tags separated by "|",
+// header row optional). Every row goes through the same checks as ShortenURL, the rate
+// limit is charged one request per row before any row is processed, and
+// results are reported row by row.
+func BulkShortenURL(c *gin.Context) {
+	var rows []bulkRow
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing CSV file"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot read CSV file"})
+			return
+		}
+		defer f.Close()
+		if rows, err = parseBulkCSV(f); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		var body []models.Request
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot Parse JSON"})
+			return
+		}
+		for _, req := range body {
+			rows = append(rows, bulkRow{Request: req})
+		}
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No rows to shorten"})
+		return
+	}
+	if len(rows) > maxBulkRows {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("At most %d rows per request", maxBulkRows),
+		})
+		return
+	}
+	passwords := 0
+	for _, row := range rows {
+		if row.ParseErr == "" && row.Request.Password != "" {
+			passwords++
+		}
+	}
+	if passwords > maxBulkPasswordRows {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("At most %d password-protected rows per request", maxBulkPasswordRows),
+		})
+		return
+	}
+
+	r2 := database.CreateClient(1)
+	defer r2.Close()
+	if ok, reset := checkQuota(r2, c.ClientIP(), len(rows)); !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":            "rate limit exceeded",
+			"rate_limit_reset": reset / time.Nanosecond / time.Minute,
+		})
+		return
+	}
+	// Charged up front, so concurrent requests can't all pass the check
+	// and then hash their passwords
+	remaining, reset := chargeQuota(r2, c.ClientIP(), len(rows))
+
+	r := database.CreateClient(0)
+	defer r.Close()
+	owner := c.GetString("userEmail")
+	results := make([]bulkResult, len(rows))
+	created := 0
+	for i, row := range rows {
+		results[i] = bulkResult{Row: i + 1, URL: row.Request.URL}
+		if row.ParseErr != "" {
+			results[i].Error = row.ParseErr
+			continue
+		}
+		link, linkErr := createLink(r, row.Request, owner)
+		if linkErr != nil {
+			results[i].Error = linkErr.Message
+			results[i].Code = linkErr.Code
+			continue
+		}
+		results[i].URL = link.URL
+		results[i].Short = os.Getenv("Domain") + "/" + link.ID
+		results[i].Status = link.Status
+		results[i].RiskScore = link.RiskScore
+		created++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created":          created,
+		"failed":           len(rows) - created,
+		"results":          results,
+		"rate_limit":       remaining,
+		"rate_limit_reset": reset,
+	})
+}
+
+// parseBulkCSV reads url,short,expiry,tags,password,max_clicks rows. If the
+// first row names its columns (it has a "url" column) they may come in any
+// order.
+func parseBulkCSV(f io.Reader) ([]bulkRow, error) {
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+
+	cols := map[string]int{"url": 0, "short": 1, "expiry": 2, "tags": 3, "password": 4, "max_clicks": 5}
+	if len(records) > 0 {
+		header := map[string]int{}
+		for i, name := range records[0] {
+			header[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, ok := header["url"]; ok {
+			cols = header
+			records = records[1:]
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]bulkRow, 0, len(records))
+	for _, record := range records {
+		row := bulkRow{Request: models.Request{
+			URL:         field(record, "url"),
+			CustomShort: field(record, "short"),
+			Password:    field(record, "password"),
+		}}
+		if expiry := field(record, "expiry"); expiry != "" {
+			hours, err := strconv.Atoi(expiry)
+			if err != nil || hours < 0 {
+				row.ParseErr = "Invalid expiry"
+			}
+			row.Request.Expiry = time.Duration(hours)
+		}
+		if maxClicks := field(record, "max_clicks"); maxClicks != "" {
+			n, err := strconv.ParseInt(maxClicks, 10, 64)
+			if err != nil || n < 0 {
+				row.ParseErr = "Invalid max_clicks"
+			}
+			row.Request.MaxClicks = n
+		}
+		if tags := field(record, "tags"); tags != "" {
+			for _, tag := range strings.Split(tags, "|") {
+				row.Request.Tags = append(row.Request.Tags, strings.TrimSpace(tag))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
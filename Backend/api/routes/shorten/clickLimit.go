@@ -0,0 +1,83 @@
+package shorten
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const goneMessage = "This link has reached its click limit and is no longer available"
+
+// consumeClick spends one resolution of a click-limited link, writing a 410
+// once it is used up. HEAD requests only check that a resolution is left, so
+// link previews don't burn one-time links.
+func consumeClick(c *gin.Context, r *redis.Client, link *models.Link) bool {
+	if link.MaxClicks == 0 {
+		return true
+	}
+	if c.Request.Method == http.MethodHead {
+		if link.Remaining <= 0 {
+			linkGone(c)
+			return false
+		}
+		return true
+	}
+	err := links.ConsumeClick(r, link.ID)
+	if err == links.ErrExhausted {
+		linkGone(c)
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Redis error"})
+		return false
+	}
+	return true
+}
+
+func linkGone(c *gin.Context) {
+	unavailable(c, http.StatusGone, "Link no longer available", goneMessage)
+}
+
+// unavailable explains why a link can't be followed, as a page for browsers
+// and as JSON for everyone else.
+func unavailable(c *gin.Context, status int, title, message string) {
+	c.Header("Cache-Control", "no-store")
+	if wantsHTML(c) {
+		c.Status(status)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		_ = unavailablePage.Execute(c.Writer, gin.H{"Title": title, "Message": message})
+		return
+	}
+	c.JSON(status, gin.H{"error": message})
+}
+
+var unavailablePage = template.Must(template.New("unavailable").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}.</p>
+</body>
+</html>`))
+
+// available stops links that can't be resolved right now: used-up click
+// limits and links held for moderation.
+func available(c *gin.Context, link *models.Link) bool {
+	if link.MaxClicks > 0 && link.Remaining <= 0 {
+		linkGone(c)
+		return false
+	}
+	if link.Status == models.LinkHeld {
+		unavailable(c, http.StatusForbidden, "Link awaiting review", "This link is awaiting review by a moderator")
+		return false
+	}
+	if link.Status == models.LinkDisabled {
+		unavailable(c, http.StatusForbidden, "Link disabled", "This link has been disabled: "+link.Disabled)
+		return false
+	}
+	return true
+}
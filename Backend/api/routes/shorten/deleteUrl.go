@@ -2,6 +2,7 @@ package shorten
 
 import (
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
 	"github.com/gin-gonic/gin"
 )
 
@@ -9,9 +10,17 @@ func DeleteURL(c *gin.Context) {
 	shortID := c.Param("shortID")
 	r := database.CreateClient(0)
 	defer r.Close()
-	if err := r.Del(database.Ctx, shortID).Err(); err != nil {
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return
+	}
+	if !canModify(c, link) {
+		return
+	}
+	if err := links.Delete(r, link); err != nil {
 		c.JSON(500, gin.H{"error": "Failed to delete URL"})
 		return
 	}
+	forgetClicks(link.ID)
 	c.JSON(200, gin.H{"message": "URL deleted successfully"})
 }
@@ -5,9 +5,11 @@ import (
 	"time"
 
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/lexical"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
 	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 )
 
 func EditURL(c *gin.Context) {
@@ -24,22 +26,62 @@ func EditURL(c *gin.Context) {
 	r := database.CreateClient(0)
 	defer r.Close()
 
-	_, err := r.Get(database.Ctx, shortID).Result()
-	if err != nil {
-		if err == redis.Nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "ShortID does not exist",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Redis error",
-			})
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return
+	}
+	if !canModify(c, link) {
+		return
+	}
+
+	if body.RedirectType != 0 && !validRedirectType(body.RedirectType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "redirect_type must be one of 301, 302, 307 or 308",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	if body.URL != "" {
+		if linkErr := validDestination(body.URL); linkErr != nil {
+			c.JSON(linkErr.Status, linkErr.body())
+			return
+		}
+		link.URL = utils.EnsureHTTPPrefix(body.URL)
+		verdict, linkErr := checkDestination(link.URL)
+		if linkErr != nil {
+			c.JSON(linkErr.Status, linkErr.body())
+			return
+		}
+		// Flags, score and lookalike all describe the old destination
+		link.Flag, link.FlaggedAt = "", time.Time{}
+		if verdict.Rating > 0 {
+			link.Flag, link.FlaggedAt = "reported_scam", now
 		}
+		assessment := lexical.Score(link.URL)
+		link.RiskScore, link.RiskReasons = assessment.Score, assessment.Reasons()
+		link.LookalikeOf = ""
+		if match := brandLookalike(link.URL); match != nil {
+			link.LookalikeOf = match.Brand
+			if link.Flag == "" {
+				link.Flag, link.FlaggedAt = "brand_lookalike", now
+			}
+		}
+	}
+	if body.RedirectType != 0 {
+		link.RedirectType = body.RedirectType
+	}
+	// An expiry of 0 keeps the link's current expiry
+	if body.Expiry > 0 {
+		link.ExpiresAt = now.Add(body.Expiry * 3600 * time.Second)
+	}
+	if linkErr := setPassword(link, body.Password); linkErr != nil {
+		c.JSON(linkErr.Status, linkErr.body())
 		return
 	}
+	link.UpdatedAt = now
 
-	err = r.Set(database.Ctx, shortID, body.URL, body.Expiry*3600*time.Second).Err()
-	if err != nil {
+	if err := links.Save(r, link); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "unable to update shortened link",
 		})
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/safety"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,14 +13,31 @@ func GetByShortID(c *gin.Context) {
 	r := database.CreateClient(0)
 	defer r.Close()
 
-	val, err := r.Get(database.Ctx, shortID).Result()
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Data not found for given shortID",
-		})
-		return 
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return
 	}
-	c.JSON(http.StatusOK,gin.H{
-		"data":val,
-	})
+	if !available(c, link) {
+		return
+	}
+	verdict, ok := scamGate(c, r, link, true)
+	if !ok {
+		return
+	}
+	if link.Protected {
+		passwordChallenge(c, shortID, http.StatusUnauthorized, "")
+		return
+	}
+	if !consumeClick(c, r, link) {
+		return
+	}
+	recordClick(c, shortID)
+	resp := gin.H{
+		"data": link.URL,
+		"tags": link.Tags,
+	}
+	if verdict.Action != safety.Allow {
+		resp["verdict"] = verdict
+	}
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,50 @@
+package shorten
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const defaultRedirectType = http.StatusFound
+
+// validRedirectType reports whether code is a redirect status we allow links to use.
+func validRedirectType(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// loadLink fetches the link for shortID, writing a 404 or 500 response when
+// it can't be loaded.
+func loadLink(c *gin.Context, r *redis.Client, shortID string) (*models.Link, bool) {
+	link, err := links.Load(r, shortID)
+	if err == links.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ShortID does not exist"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Redis error"})
+		return nil, false
+	}
+	return link, true
+}
+
+// canModify checks that the JWT user owns link, or is an admin, and writes a
+// 403 response when they don't. Links created anonymously have no owner and
+// can only be changed by admins.
+func canModify(c *gin.Context, link *models.Link) bool {
+	email := c.GetString("userEmail")
+	if (link.Owner != "" && link.Owner == email) || utils.IsAdmin(email) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this link"})
+	return false
+}
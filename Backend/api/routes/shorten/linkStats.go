@@ -0,0 +1,80 @@
+package shorten
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/analytics"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/gin-gonic/gin"
+)
+
+// recordClick queues an analytics event for a resolution of shortID. HEAD
+// requests come from link previews and crawlers, not visitors, so they
+// aren't counted.
+func recordClick(c *gin.Context, shortID string) {
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	analytics.Record(analytics.Event{
+		ShortID:   shortID,
+		At:        time.Now(),
+		Referrer:  c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		Visitor:   analytics.HashIP(c.ClientIP()),
+	})
+}
+
+// forgetClicks drops the analytics kept for shortID, when its link is
+// deleted or the ID is handed to a new link.
+func forgetClicks(shortID string) {
+	r5 := database.CreateClient(5)
+	defer r5.Close()
+	if err := analytics.Delete(r5, shortID); err != nil {
+		log.Println("failed to clear analytics for", shortID+":", err)
+	}
+}
+
+// LinkStats returns click statistics for a link. Only its owner may see them.
+//
+// Query parameters: hours (hourly buckets, default 48) and days (daily
+// buckets, default 30).
+func LinkStats(c *gin.Context) {
+	shortID := c.Param("shortID")
+	hours, err1 := strconv.Atoi(c.DefaultQuery("hours", "48"))
+	days, err2 := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err1 != nil || err2 != nil || hours < 1 || hours > 24*14 || days < 1 || days > 366 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be 1-336 and days 1-366"})
+		return
+	}
+
+	r := database.CreateClient(0)
+	defer r.Close()
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return
+	}
+	if link.Owner == "" || link.Owner != c.GetString("userEmail") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this link"})
+		return
+	}
+
+	r5 := database.CreateClient(5)
+	defer r5.Close()
+	stats, err := analytics.Load(r5, shortID, hours, days, 10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"short":           shortID,
+		"total_clicks":    link.Clicks,
+		"unique_visitors": stats.UniqueVisitors,
+		"hourly":          stats.Hourly,
+		"daily":           stats.Daily,
+		"top_referrers":   stats.TopReferrers,
+	})
+}
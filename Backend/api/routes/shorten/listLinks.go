@@ -0,0 +1,157 @@
+package shorten
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// listCursor marks the last item of a page: its sort key and ID, so the next
+// page starts right after it even if links are added in the meantime.
+type listCursor struct {
+	Key int64  `json:"k"`
+	ID  string `json:"id"`
+}
+
+// ListLinks returns the JWT user's links, one page at a time. Creation and
+// expiry order page through the owner's indexes; click counts change on
+// every visit and aren't indexed, so that order is sorted in memory.
+//
+// Query parameters: sort (created, expires or clicks), order (desc or asc),
+// tag, domain, limit and cursor (as returned in next_cursor).
+func ListLinks(c *gin.Context) {
+	sortBy := c.DefaultQuery("sort", "created")
+	var sortKey func(*models.Link) int64
+	switch sortBy {
+	case links.ByCreated:
+		sortKey = func(l *models.Link) int64 { return l.CreatedAt.Unix() }
+	case links.ByExpiry:
+		// Links that never expire sort after all expiring ones
+		sortKey = links.ExpiryScore
+	case "clicks":
+		sortKey = func(l *models.Link) int64 { return l.Clicks }
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of created, expires or clicks"})
+		return
+	}
+	desc := c.DefaultQuery("order", "desc") != "asc"
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return
+	}
+	limit = min(limit, maxPageSize)
+
+	var cursor *listCursor
+	if raw := c.Query("cursor"); raw != "" {
+		cursor = &listCursor{}
+		b, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil || json.Unmarshal(b, cursor) != nil || cursor.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+
+	owner, tag := c.GetString("userEmail"), c.Query("tag")
+	domain := strings.ToLower(strings.TrimPrefix(c.Query("domain"), "www."))
+	keep := func(link *models.Link) bool {
+		return (tag == "" || slices.Contains(link.Tags, tag)) &&
+			(domain == "" || matchesDomain(link.URL, domain))
+	}
+
+	r := database.CreateClient(0)
+	defer r.Close()
+	var page []*models.Link
+	var more bool
+	if sortBy == "clicks" {
+		page, more, err = sortedPage(r, owner, tag, desc, cursor, limit, sortKey, keep)
+	} else {
+		var afterKey int64
+		var afterID string
+		if cursor != nil {
+			afterKey, afterID = cursor.Key, cursor.ID
+		}
+		page, more, err = links.OwnedPage(r, owner, sortBy, desc, afterKey, afterID, limit, keep)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load links"})
+		return
+	}
+
+	resp := gin.H{"links": nonNilLinks(page), "next_cursor": nil}
+	if more && len(page) > 0 {
+		last := page[len(page)-1]
+		b, _ := json.Marshal(listCursor{Key: sortKey(last), ID: last.ID})
+		resp["next_cursor"] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// sortedPage loads all of owner's links and pages through them sorted by
+// sortKey, for orders no index covers.
+func sortedPage(r *redis.Client, owner, tag string, desc bool, cursor *listCursor, limit int, sortKey func(*models.Link) int64, keep func(*models.Link) bool) ([]*models.Link, bool, error) {
+	owned, err := links.Owned(r, owner, tag)
+	if err != nil {
+		return nil, false, err
+	}
+	filtered := owned[:0]
+	for _, link := range owned {
+		if keep(link) {
+			filtered = append(filtered, link)
+		}
+	}
+
+	// before reports whether a sorts ahead of b in the requested order
+	before := func(ka int64, ida string, kb int64, idb string) bool {
+		if ka != kb {
+			return (ka > kb) == desc
+		}
+		return (ida > idb) == desc
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return before(sortKey(filtered[i]), filtered[i].ID, sortKey(filtered[j]), filtered[j].ID)
+	})
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return before(cursor.Key, cursor.ID, sortKey(filtered[i]), filtered[i].ID)
+		})
+	}
+	end := min(start+limit, len(filtered))
+	return filtered[start:end], end < len(filtered), nil
+}
+
+func nonNilLinks(page []*models.Link) []*models.Link {
+	if page == nil {
+		return []*models.Link{}
+	}
+	return page
+}
+
+// matchesDomain reports whether rawURL points at domain or one of its subdomains.
+func matchesDomain(rawURL, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,86 @@
+package shorten
+
+import (
+	"net/http"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// GetHeldLinks lists anonymous links awaiting approval, oldest first. Those
+// whose destination had been reported as a scam carry the reported_scam flag.
+func GetHeldLinks(c *gin.Context) {
+	r := database.CreateClient(0)
+	defer r.Close()
+	held, err := links.Held(r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load held links"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": held})
+}
+
+// ApproveLink lets a held link resolve.
+func ApproveLink(c *gin.Context) {
+	r := database.CreateClient(0)
+	defer r.Close()
+	link, ok := heldLink(c, r, c.Param("shortID"))
+	if !ok {
+		return
+	}
+	if err := links.Release(r, link.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Link approved"})
+}
+
+// RejectLink deletes a held link.
+func RejectLink(c *gin.Context) {
+	r := database.CreateClient(0)
+	defer r.Close()
+	link, ok := heldLink(c, r, c.Param("shortID"))
+	if !ok {
+		return
+	}
+	if err := links.Delete(r, link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject link"})
+		return
+	}
+	forgetClicks(link.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Link rejected and deleted"})
+}
+
+func heldLink(c *gin.Context, r *redis.Client, shortID string) (*models.Link, bool) {
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return nil, false
+	}
+	if link.Status != models.LinkHeld {
+		c.JSON(http.StatusConflict, gin.H{"error": "Link is not awaiting moderation"})
+		return nil, false
+	}
+	return link, true
+}
+
+// RestoreLink re-enables a single link disabled by a takedown.
+func RestoreLink(c *gin.Context) {
+	r := database.CreateClient(0)
+	defer r.Close()
+	link, ok := loadLink(c, r, c.Param("shortID"))
+	if !ok {
+		return
+	}
+	if link.Status != models.LinkDisabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Link is not disabled"})
+		return
+	}
+	if _, err := links.Restore(r, link.ID, link.TakedownID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Link restored"})
+}
@@ -0,0 +1,141 @@
+package shorten
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Wrong passwords are counted per link in DB 1, next to the per-IP quotas,
+// and the link is locked for the rest of the window once the limit is hit.
+const (
+	maxPasswordFailures = 10
+	passwordFailWindow  = 15 * time.Minute
+)
+
+func passwordFailKey(shortID string) string {
+	return "pwfail:" + shortID
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+var challengePage = template.Must(template.New("challenge").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Password required</title></head>
+<body>
+<h1>This link is password protected</h1>
+{{if .Error}}<p style="color:#b91c1c">{{.Error}}</p>{{end}}
+<form method="POST" action="/{{.ShortID}}">
+<input type="password" name="password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>`))
+
+// wantsHTML reports whether the client is a browser rather than an API client.
+func wantsHTML(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+}
+
+// passwordChallenge asks for the password of a protected link, as a form for
+// browsers and as JSON for everyone else.
+func passwordChallenge(c *gin.Context, shortID string, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+	if wantsHTML(c) {
+		c.Status(status)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		_ = challengePage.Execute(c.Writer, gin.H{"ShortID": shortID, "Error": message})
+		return
+	}
+	if message == "" {
+		message = "Password required"
+	}
+	c.JSON(status, gin.H{
+		"error":             message,
+		"password_required": true,
+		"unlock":            "/" + shortID,
+	})
+}
+
+// UnlockLink checks the password submitted for a protected link and, when it
+// matches, sends the visitor on: a 303 redirect for form posts, the URL as
+// JSON for API clients.
+func UnlockLink(c *gin.Context) {
+	shortID := c.Param("shortID")
+	var body struct {
+		Password string `json:"password" form:"password"`
+	}
+	if err := c.ShouldBind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	r := database.CreateClient(0)
+	defer r.Close()
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return
+	}
+	if link.PasswordHash == "" {
+		c.Redirect(http.StatusSeeOther, "/"+shortID)
+		return
+	}
+	if !available(c, link) {
+		return
+	}
+	apiClient := c.ContentType() == gin.MIMEJSON
+	if _, ok := scamGate(c, r, link, apiClient); !ok {
+		return
+	}
+
+	r2 := database.CreateClient(1)
+	defer r2.Close()
+	key := passwordFailKey(shortID)
+	if failures, _ := r2.Get(database.Ctx, key).Int(); failures >= maxPasswordFailures {
+		ttl, _ := r2.TTL(database.Ctx, key).Result()
+		c.Header("Retry-After", strconv.Itoa(int(ttl.Seconds())))
+		passwordChallenge(c, shortID, http.StatusTooManyRequests, "Too many wrong passwords, try again later")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(body.Password)) != nil {
+		if n, err := r2.Incr(database.Ctx, key).Result(); err == nil && n == 1 {
+			r2.Expire(database.Ctx, key, passwordFailWindow)
+		}
+		passwordChallenge(c, shortID, http.StatusUnauthorized, "Wrong password")
+		return
+	}
+
+	if !consumeClick(c, r, link) {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	recordClick(c, shortID)
+	if apiClient {
+		c.JSON(http.StatusOK, gin.H{"data": link.URL, "tags": link.Tags})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, link.URL)
+}
+
+// setPassword protects link with password, if one was given.
+func setPassword(link *models.Link, password string) *linkError {
+	if password == "" {
+		return nil
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return &linkError{Status: http.StatusBadRequest, Message: "Invalid password"}
+	}
+	link.PasswordHash = hash
+	link.Protected = true
+	return nil
+}
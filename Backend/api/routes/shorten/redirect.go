@@ -0,0 +1,67 @@
+package shorten
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/safety"
+	"github.com/gin-gonic/gin"
+)
+
+// Browsers may cache permanent redirects for as long as we let them, so keep
+// the window short enough that edits, deletions and takedowns take effect
+// within minutes, and keep it out of shared caches. Links with a click limit
+// are never cached, or cached clicks would go uncounted, and neither are
+// flagged links or reported destinations, which must stay checkable.
+const maxPermanentCacheAge = 5 * time.Minute
+
+// Redirect resolves a short link and sends the client to its destination
+// using the redirect status chosen when the link was created.
+func Redirect(c *gin.Context) {
+	shortID := c.Param("shortID")
+	r := database.CreateClient(0)
+	defer r.Close()
+
+	link, ok := loadLink(c, r, shortID)
+	if !ok {
+		return
+	}
+
+	if !available(c, link) {
+		return
+	}
+	verdict, ok := scamGate(c, r, link, false)
+	if !ok {
+		return
+	}
+	if link.Protected {
+		passwordChallenge(c, shortID, http.StatusUnauthorized, "")
+		return
+	}
+
+	status := link.RedirectType
+	if !validRedirectType(status) {
+		status = defaultRedirectType
+	}
+
+	permanent := status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+	cacheable := link.MaxClicks == 0 && link.Flag == "" && verdict.Action == safety.Allow
+	if permanent && cacheable {
+		maxAge := maxPermanentCacheAge
+		if !link.ExpiresAt.IsZero() {
+			if ttl := time.Until(link.ExpiresAt); ttl < maxAge {
+				maxAge = max(ttl, 0)
+			}
+		}
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		c.Header("Cache-Control", "private, no-cache, no-store, must-revalidate")
+	}
+	if !consumeClick(c, r, link) {
+		return
+	}
+	recordClick(c, shortID)
+	c.Redirect(status, link.URL)
+}
@@ -0,0 +1,61 @@
+package shorten
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/slugs"
+	"github.com/gin-gonic/gin"
+)
+
+// GetReservedSlugs lists the reserved words, grouped by where they come from.
+func GetReservedSlugs(c *gin.Context) {
+	reserved, err := slugs.Reserved()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reserved slugs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reserved": reserved})
+}
+
+// AddReservedSlug reserves a word at runtime.
+func AddReservedSlug(c *gin.Context) {
+	var body struct {
+		Slug string `json:"slug" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(body.Slug))
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug must not be empty"})
+		return
+	}
+	r := database.CreateClient(3)
+	defer r.Close()
+	if err := r.SAdd(database.Ctx, slugs.ReservedKey, slug).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve slug"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Slug reserved", "slug": slug})
+}
+
+// RemoveReservedSlug releases a word reserved through AddReservedSlug. Words
+// from the configuration or the router can't be released here.
+func RemoveReservedSlug(c *gin.Context) {
+	r := database.CreateClient(3)
+	defer r.Close()
+	slug := strings.ToLower(c.Param("slug"))
+	removed, err := r.SRem(database.Ctx, slugs.ReservedKey, slug).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release slug"})
+		return
+	}
+	if removed == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Slug is not on the runtime reserved list"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Slug released", "slug": slug})
+}
@@ -0,0 +1,199 @@
+package shorten
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/safety"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Warning: reported link</title></head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The page it leads to has been reported as a scam by {{.Rating}} people.</p>
+<p>Destination: <code>{{.URL}}</code></p>
+<form method="POST" action="/{{.ShortID}}/continue">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Continue anyway</button>
+</form>
+</body>
+</html>`))
+
+var blockedPage = template.Must(template.New("blocked").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Link blocked</title></head>
+<body>
+<h1>This link has been blocked</h1>
+<p>The page it leads to has been {{if .Verified}}confirmed as a scam{{else}}reported as a scam by {{.Rating}} people{{end}}, so we won't send you there.</p>
+</body>
+</html>`))
+
+// Continuing past a warning is remembered with a cookie scoped to the link,
+// so the choice belongs to the visitor who made it and can't be handed on in
+// a URL. The warning page sets a random nonce cookie and embeds the same
+// nonce in its form; ContinueLink only sets the acknowledgement when the two
+// match, so another site can't post the form on a visitor's behalf.
+const (
+	warnCookie   = "scam_warn"
+	ackCookie    = "scam_ack"
+	warnLifetime = 10 * time.Minute
+	ackLifetime  = 10 * time.Minute
+)
+
+// ackSecret is read on first use rather than at init, so it sees what
+// godotenv loads from .env.
+var ackSecret = sync.OnceValue(func() []byte {
+	return []byte(os.Getenv("jwt_secret"))
+})
+
+var errNoAckSecret = errors.New("jwt_secret is not set, scam warnings can't be acknowledged")
+
+// ackToken signs the link ID and an expiry time. It refuses to sign with an
+// empty key, which would let anyone forge the token.
+func ackToken(shortID string, expires time.Time) (string, error) {
+	secret := ackSecret()
+	if len(secret) == 0 {
+		return "", errNoAckSecret
+	}
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(shortID + "|" + exp))
+	return exp + "." + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// acknowledged reports whether the visitor already chose to continue past a
+// scam warning for this link and the choice hasn't expired.
+func acknowledged(c *gin.Context, shortID string) bool {
+	token, err := c.Cookie(ackCookie)
+	if err != nil {
+		return false
+	}
+	exp, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	want, err := ackToken(shortID, time.Unix(unix, 0))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(want))
+}
+
+// warnNonce sets a fresh nonce cookie for the warning about shortID and
+// returns it for the page's form.
+func warnNonce(c *gin.Context, shortID string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	nonce := hex.EncodeToString(b)
+	setLinkCookie(c, shortID, warnCookie, nonce, warnLifetime, http.SameSiteStrictMode)
+	return nonce
+}
+
+func setLinkCookie(c *gin.Context, shortID, name, value string, lifetime time.Duration, sameSite http.SameSite) {
+	c.SetSameSite(sameSite)
+	c.SetCookie(name, value, int(lifetime.Seconds()), "/"+shortID, "", c.Request.TLS != nil, true)
+}
+
+// ContinueLink records that the visitor wants to go on to a link despite the
+// scam warning, then sends them back to it. Blocked links stay blocked: the
+// gate runs again on the way through.
+func ContinueLink(c *gin.Context) {
+	shortID := c.Param("shortID")
+	nonce, err := c.Cookie(warnCookie)
+	token := c.PostForm("token")
+	if err != nil || nonce == "" || !hmac.Equal([]byte(nonce), []byte(token)) {
+		c.Redirect(http.StatusSeeOther, "/"+shortID)
+		return
+	}
+	setLinkCookie(c, shortID, warnCookie, "", -1, http.SameSiteStrictMode)
+	ack, err := ackToken(shortID, time.Now().Add(ackLifetime))
+	if err != nil {
+		log.Println("scam gate:", err)
+		c.Redirect(http.StatusSeeOther, "/"+shortID)
+		return
+	}
+	setLinkCookie(c, shortID, ackCookie, ack, ackLifetime, http.SameSiteLaxMode)
+	c.Redirect(http.StatusSeeOther, "/"+shortID)
+}
+
+// scamGate checks the link's destination against the scam stores, flagging
+// links whose destination has since been verified as a scam. Blocked
+// destinations get a 403; reported ones get a warning page with a "continue
+// anyway" button unless the visitor already pressed it. API clients are not
+// stopped by warnings: the verdict is returned so they can warn themselves.
+func scamGate(c *gin.Context, r *redis.Client, link *models.Link, apiClient bool) (safety.Verdict, bool) {
+	verdict, err := safety.Check(link.URL)
+	if err != nil {
+		// A broken lookup shouldn't take every link down with it
+		log.Println("scam check failed for", link.ID+":", err)
+		return verdict, true
+	}
+	// The destination may have been verified after the link was created
+	if verdict.Verified && link.Flag != "verified_scam" {
+		if err := links.SetFlag(r, link.ID, "verified_scam"); err != nil {
+			log.Println("failed to flag", link.ID+":", err)
+		}
+	}
+	switch verdict.Action {
+	case safety.Block:
+		c.Header("Cache-Control", "no-store")
+		if !apiClient && wantsHTML(c) {
+			c.Status(http.StatusForbidden)
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			_ = blockedPage.Execute(c.Writer, verdict)
+			return verdict, false
+		}
+		message := "The destination of this link has been reported as a scam"
+		if verdict.Verified {
+			message = "The destination of this link is a confirmed scam"
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   message,
+			"verdict": verdict,
+		})
+		return verdict, false
+	case safety.Warn:
+		if apiClient || acknowledged(c, link.ID) {
+			return verdict, true
+		}
+		c.Header("Cache-Control", "no-store")
+		token := warnNonce(c, link.ID)
+		if wantsHTML(c) {
+			c.Status(http.StatusOK)
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			_ = warningPage.Execute(c.Writer, gin.H{"ShortID": link.ID, "URL": link.URL, "Rating": verdict.Rating, "Token": token})
+			return verdict, false
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "The destination of this link has been reported as a scam",
+			"verdict":  verdict,
+			"continue": "/" + link.ID + "/continue",
+			"token":    token,
+		})
+		return verdict, false
+	}
+	return verdict, true
+}
@@ -1,18 +1,25 @@
 package shorten
 
 import (
+	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/abdulhameedsk/URL-Shortner/api/brands"
+	"github.com/abdulhameedsk/URL-Shortner/api/codegen"
 	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/lexical"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
 	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/safety"
+	"github.com/abdulhameedsk/URL-Shortner/api/slugs"
 	"github.com/abdulhameedsk/URL-Shortner/api/utils"
 	"github.com/asaskevich/govalidator"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
 )
 
 func ShortenURL(c *gin.Context) {
@@ -24,76 +31,290 @@ func ShortenURL(c *gin.Context) {
 	}
 	r2 := database.CreateClient(1)
 	defer r2.Close()
-	//Gets value with key ClientIp
-	val, err := r2.Get(database.Ctx, c.ClientIP()).Result()
-	if err == redis.Nil {
-		//30*60 is expiration time
-		_ = r2.Set(database.Ctx, c.ClientIP(), os.Getenv("API_QUOTA"), 30*60*time.Second).Err()
-	} else {
-		val, _ = r2.Get(database.Ctx, c.ClientIP()).Result()
-		valInt, _ := strconv.Atoi(val)
-		if valInt <= 0 {
-			//This gets the remaining time-to-live (TTL) of a key in Redis — in this case, the key is the client’s IP address.
-			limit, _ := r2.TTL(database.Ctx, c.ClientIP()).Result()
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error":            "rate limit exceeded",
-				"rate_limit_reset": limit / time.Nanosecond / time.Minute,
-			})
-			return
-
-		}
-	}
-	if !govalidator.IsURL(body.URL) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid URL",
-		})
-		return
-	}
-	if !utils.IsDifferentDomain(body.URL) {
+	if ok, reset := checkQuota(r2, c.ClientIP(), 1); !ok {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "You Can't Shorten this URL :.( )",
+			"error":            "rate limit exceeded",
+			"rate_limit_reset": reset / time.Nanosecond / time.Minute,
 		})
 		return
 	}
-	body.URL = utils.EnsureHTTPPrefix(body.URL)
-	var id string
-	if body.CustomShort == "" {
-		id = uuid.New().String()[:6]
-	} else {
-		id = body.CustomShort
-	}
+
 	r := database.CreateClient(0)
 	defer r.Close()
-	val, _ = r.Get(database.Ctx, id).Result()
-	if val != "" {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": "URL short already exists",
-		})
+	link, linkErr := createLink(r, body, c.GetString("userEmail"))
+	if linkErr != nil {
+		c.JSON(linkErr.Status, linkErr.body())
 		return
 	}
 
-	if body.Expiry == 0 {
-		body.Expiry = 24
-	}
-	r.Set(database.Ctx, id, body.URL, body.Expiry*3600*time.Second).Err()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Unable to connect to redis server",
-		})
-		return
-	}
 	resp := models.Responce{
-		Expiry:          body.Expiry,
+		Expiry:          link.ExpiresAt.Sub(link.CreatedAt) / time.Hour,
 		XRateLimitReset: 30,
 		XRateRemaining:  10,
-		URL:             body.URL,
+		URL:             link.URL,
 		CustomShort:     "",
 	}
-	r2.Decr(database.Ctx, c.ClientIP())
-	val, _ = r2.Get(database.Ctx, c.ClientIP()).Result()
-	resp.XRateRemaining, _ = strconv.Atoi(val)
-	ttl, _ := r2.TTL(database.Ctx, c.ClientIP()).Result()
-	resp.XRateLimitReset = ttl / time.Nanosecond / time.Minute
-	resp.CustomShort = os.Getenv("Domain") + "/" + id
+	resp.XRateRemaining, resp.XRateLimitReset = chargeQuota(r2, c.ClientIP(), 1)
+	resp.CustomShort = os.Getenv("Domain") + "/" + link.ID
+	resp.Status = link.Status
+	resp.RiskScore, resp.RiskReasons = link.RiskScore, link.RiskReasons
+	resp.LookalikeOf = link.LookalikeOf
+	if link.Status == models.LinkHeld {
+		// Created, but it won't resolve until an admin approves it
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// linkError is a failure to create one link, with the status ShortenURL
+// answers it with.
+type linkError struct {
+	Status  int
+	Message string
+	Code    string          // machine-readable reason, when there is one
+	Verdict *safety.Verdict // set when the destination failed the scam check
+}
+
+func (e *linkError) body() gin.H {
+	body := gin.H{"error": e.Message}
+	if e.Code != "" {
+		body["code"] = e.Code
+	}
+	if e.Verdict != nil {
+		body["verdict"] = e.Verdict
+	}
+	return body
+}
+
+// checkDestination refuses admin-verified scam destinations and returns the
+// verdict for everything else. A failing lookup lets the URL through rather
+// than blocking all shortening.
+func checkDestination(url string) (safety.Verdict, *linkError) {
+	verdict, err := safety.Check(url)
+	if err != nil {
+		log.Println("scam check failed for", url+":", err)
+		return safety.Verdict{Action: safety.Allow, Risk: safety.RiskNone}, nil
+	}
+	if verdict.Verified {
+		return verdict, &linkError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "This destination is a confirmed scam and can't be shortened",
+			Code:    "destination_blocked",
+			Verdict: &verdict,
+		}
+	}
+	return verdict, nil
+}
+
+// validDestination checks that url is a URL we are willing to point a link
+// at, whether on creation or on edit.
+func validDestination(url string) *linkError {
+	if !govalidator.IsURL(url) {
+		return &linkError{Status: http.StatusBadRequest, Message: "Invalid URL"}
+	}
+	if !utils.IsDifferentDomain(url) {
+		return &linkError{Status: http.StatusServiceUnavailable, Message: "You Can't Shorten this URL :.( )"}
+	}
+	return nil
+}
+
+// brandLookalike returns the protected brand url's host imitates, if any. A
+// failing lookup is logged and lets the URL through unflagged.
+func brandLookalike(url string) *brands.Match {
+	set, err := brands.Load()
+	if err != nil {
+		log.Println("brand list lookup failed:", err)
+		return nil
+	}
+	return set.Match(url)
+}
+
+// createLink validates body with the rules shared by every way of shortening
+// a URL and stores the resulting link, owned by owner if one is given.
+func createLink(r *redis.Client, body models.Request, owner string) (*models.Link, *linkError) {
+	if linkErr := validDestination(body.URL); linkErr != nil {
+		return nil, linkErr
+	}
+	if body.RedirectType == 0 {
+		body.RedirectType = defaultRedirectType
+	}
+	if !validRedirectType(body.RedirectType) {
+		return nil, &linkError{Status: http.StatusBadRequest, Message: "redirect_type must be one of 301, 302, 307 or 308"}
+	}
+	body.URL = utils.EnsureHTTPPrefix(body.URL)
+	verdict, linkErr := checkDestination(body.URL)
+	if linkErr != nil {
+		return nil, linkErr
+	}
+
+	if body.Expiry < 0 {
+		return nil, &linkError{Status: http.StatusBadRequest, Message: "Invalid expiry"}
+	}
+	if body.Expiry == 0 {
+		body.Expiry = 24
+	}
+	tags := []string{}
+	for _, tag := range body.Tags {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	now := time.Now().UTC()
+	link := &models.Link{
+		ID:           body.CustomShort,
+		URL:          body.URL,
+		Owner:        owner,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ExpiresAt:    now.Add(body.Expiry * 3600 * time.Second),
+		Tags:         tags,
+		RedirectType: body.RedirectType,
+		Status:       models.LinkActive,
+	}
+	assessment := lexical.Score(body.URL)
+	link.RiskScore, link.RiskReasons = assessment.Score, assessment.Reasons()
+	// Anonymous links wait for a moderator, since nobody answers for them;
+	// links with an owner go live, flagged when the destination was reported
+	if owner == "" {
+		link.Status = models.LinkHeld
+	}
+	if verdict.Rating > 0 {
+		link.Flag = "reported_scam"
+		link.FlaggedAt = now
+	}
+	// Lookalikes of protected brands go live, flagged with the brand
+	if match := brandLookalike(link.URL); match != nil {
+		link.LookalikeOf = match.Brand
+		if link.Flag == "" {
+			link.Flag = "brand_lookalike"
+			link.FlaggedAt = now
+		}
+	}
+	plain := body.CustomShort == "" && body.Password == "" && body.MaxClicks == 0 && !body.OneTime
+	if owner != "" && plain && link.Status == models.LinkActive {
+		if existing, err := sameDestination(r, link); err != nil {
+			log.Println("duplicate lookup failed for", link.URL+":", err)
+		} else if existing != nil {
+			return existing, nil
+		}
+	}
+	if linkErr := setPassword(link, body.Password); linkErr != nil {
+		return nil, linkErr
+	}
+	if body.OneTime {
+		if body.MaxClicks > 1 {
+			return nil, &linkError{Status: http.StatusBadRequest, Message: "one_time links can't have max_clicks above 1"}
+		}
+		body.MaxClicks = 1
+	}
+	if body.MaxClicks < 0 {
+		return nil, &linkError{Status: http.StatusBadRequest, Message: "Invalid max_clicks"}
+	}
+	link.MaxClicks = body.MaxClicks
+	link.Remaining = body.MaxClicks
+	if body.CustomShort != "" {
+		if err := slugs.Check(body.CustomShort); err != nil {
+			return nil, &linkError{Status: http.StatusBadRequest, Message: err.Error()}
+		}
+		if err := links.Create(r, link); err == links.ErrTaken {
+			return nil, &linkError{Status: http.StatusForbidden, Message: "URL short already exists"}
+		} else if err != nil {
+			return nil, &linkError{Status: http.StatusInternalServerError, Message: "Unable to connect to redis server"}
+		}
+		forgetClicks(link.ID)
+		return link, nil
+	}
+
+	// Generated codes are claimed atomically; on a collision ask for another
+	gen, cfg := codegen.Default()
+	for attempt := 0; attempt < cfg.MaxAttempts; attempt++ {
+		id, err := gen.Next(link.URL, attempt)
+		if err != nil {
+			return nil, &linkError{Status: http.StatusInternalServerError, Message: "Unable to generate a short code"}
+		}
+		if slugs.Allowed(id) != nil {
+			continue
+		}
+		link.ID = id
+		err = links.Create(r, link)
+		if err == nil {
+			// The code may have belonged to a link that expired
+			forgetClicks(link.ID)
+			return link, nil
+		}
+		if err != links.ErrTaken {
+			return nil, &linkError{Status: http.StatusInternalServerError, Message: "Unable to connect to redis server"}
+		}
+	}
+	return nil, &linkError{Status: http.StatusServiceUnavailable, Message: "Could not allocate a free short code, please retry"}
+}
+
+// sameDestination looks for a plain link (no password, no click limit) that
+// link's owner already has to the same canonical
+// destination with the same redirect type. If there is one it takes on the
+// later of the two expiries and any new tags, and is returned in place of
+// creating a duplicate.
+func sameDestination(r *redis.Client, link *models.Link) (*models.Link, error) {
+	candidates, err := links.ByDestination(r, link.URL)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range candidates {
+		if existing.Owner != link.Owner || existing.Status != models.LinkActive ||
+			existing.Protected || existing.MaxClicks > 0 || existing.RedirectType != link.RedirectType {
+			continue
+		}
+		changed := false
+		if !existing.ExpiresAt.IsZero() && existing.ExpiresAt.Before(link.ExpiresAt) {
+			existing.ExpiresAt = link.ExpiresAt
+			changed = true
+		}
+		for _, tag := range link.Tags {
+			if !slices.Contains(existing.Tags, tag) {
+				existing.Tags = append(existing.Tags, tag)
+				changed = true
+			}
+		}
+		if changed {
+			existing.UpdatedAt = link.UpdatedAt
+			if err := links.Save(r, existing); err != nil {
+				return nil, err
+			}
+		}
+		return existing, nil
+	}
+	return nil, nil
+}
+
+// checkQuota reports whether ip may create n more links in its current rate
+// limit window, opening a fresh window of API_QUOTA requests if it has none.
+// When it may not, the time until the window resets is returned.
+func checkQuota(r2 *redis.Client, ip string, n int) (bool, time.Duration) {
+	//Gets value with key ClientIp
+	val, err := r2.Get(database.Ctx, ip).Result()
+	if err == redis.Nil {
+		//30*60 is expiration time
+		_ = r2.Set(database.Ctx, ip, os.Getenv("API_QUOTA"), 30*60*time.Second).Err()
+		val = os.Getenv("API_QUOTA")
+		// The request that opens a window has always been let through
+		if n == 1 {
+			return true, 0
+		}
+	}
+	valInt, _ := strconv.Atoi(val)
+	if valInt < n {
+		//This gets the remaining time-to-live (TTL) of a key in Redis — in this case, the key is the client’s IP address.
+		limit, _ := r2.TTL(database.Ctx, ip).Result()
+		return false, limit
+	}
+	return true, 0
+}
+
+// chargeQuota takes n requests off ip's quota and returns what is left and
+// the minutes until the window resets.
+func chargeQuota(r2 *redis.Client, ip string, n int) (int, time.Duration) {
+	remaining, _ := r2.DecrBy(database.Ctx, ip, int64(n)).Result()
+	ttl, _ := r2.TTL(database.Ctx, ip).Result()
+	return int(remaining), ttl / time.Nanosecond / time.Minute
+}
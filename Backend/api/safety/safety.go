@@ -0,0 +1,190 @@
+// Package safety decides whether a destination URL is safe to send visitors
+// to, based on the community scam reports in DB 2 and the admin-verified
+// scams in DB 3.
+package safety
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/lexical"
+	"github.com/abdulhameedsk/URL-Shortner/api/scamrules"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/go-redis/redis/v8"
+)
+
+// Actions a verdict can carry.
+const (
+	Allow = "allow"
+	Warn  = "warn"
+	Block = "block"
+)
+
+// Verdict is the outcome of checking one URL.
+type Verdict struct {
+	Verified bool   `json:"verified"`        // covered by an admin-verified entry
+	Scope    string `json:"scope,omitempty"` // scope of the most specific entry covering it
+	Entry    string `json:"entry,omitempty"` // that entry's URL, prefix, pattern or domain
+	Rating   int    `json:"rating"`          // community rating, 0 if never reported
+	Reports  int    `json:"reports"`         // times it was reported, votes excluded
+	Risk     string `json:"risk"`            // none, low, medium or high
+	Action   string `json:"action"`          // allow, warn or block
+
+	// Lexical is how suspicious the URL looks, for URLs with no entry or
+	// report to go on
+	Lexical *lexical.Assessment `json:"lexical,omitempty"`
+}
+
+// Risk levels: high for verified scams, medium for reports at or above the
+// warning threshold, low for reports below it. URLs nobody has reported are
+// rated on their lexical score alone, never above medium and never blocked.
+const (
+	RiskNone   = "none"
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Config holds the thresholds and actions, read from the environment:
+// SCAM_VERIFIED_ACTION (block or warn, default block), SCAM_REPORTED_ACTION
+// (warn or block, default warn) and SCAM_WARN_THRESHOLD (the rating at which
+// reported URLs get the reported action, default 3).
+type Config struct {
+	VerifiedAction string
+	ReportedAction string
+	WarnThreshold  int
+}
+
+func ConfigFromEnv() Config {
+	cfg := Config{
+		VerifiedAction: envAction("SCAM_VERIFIED_ACTION", Block),
+		ReportedAction: envAction("SCAM_REPORTED_ACTION", Warn),
+		WarnThreshold:  3,
+	}
+	if n, err := strconv.Atoi(os.Getenv("SCAM_WARN_THRESHOLD")); err == nil && n > 0 {
+		cfg.WarnThreshold = n
+	}
+	return cfg
+}
+
+func envAction(name, fallback string) string {
+	switch v := strings.ToLower(os.Getenv(name)); v {
+	case Allow, Warn, Block:
+		return v
+	}
+	return fallback
+}
+
+// Lexical scores at which unreported URLs count as low and medium risk.
+const (
+	lexicalLow    = 20
+	lexicalMedium = 50
+)
+
+func lexicalRisk(score int) string {
+	switch {
+	case score >= lexicalMedium:
+		return RiskMedium
+	case score >= lexicalLow:
+		return RiskLow
+	}
+	return RiskNone
+}
+
+// Check looks url up in both scam stores and applies the configured actions.
+func Check(url string) (Verdict, error) {
+	verdicts, err := CheckAll([]string{url})
+	if err != nil {
+		return Verdict{Action: Allow, Risk: RiskNone}, err
+	}
+	return verdicts[0], nil
+}
+
+// CheckAll is Check for many URLs at once, with one round trip to the
+// report store. Every lookup is by key: entries come from the in-memory
+// scamrules index and reports from their canonical key.
+func CheckAll(urls []string) ([]Verdict, error) {
+	cfg := ConfigFromEnv()
+	set, err := scamrules.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	r := database.CreateClient(2)
+	defer r.Close()
+	pipe := r.Pipeline()
+	cmds := make([][]*redis.StringCmd, len(urls))
+	for i, url := range urls {
+		// Reports are keyed canonically (records from before that are moved
+		// by "migrate canonical"); a URL whose key names other data kept in
+		// DB 2 can't have a report
+		if key := canonical.Key(url); scams.IsReportKey(key) {
+			cmds[i] = append(cmds[i], pipe.Get(database.Ctx, key))
+		}
+	}
+	// Exec returns the first failed command's error, but a key holding
+	// something other than a report only means there is no report there
+	if _, err := pipe.Exec(database.Ctx); err != nil && err != redis.Nil && !isReplyError(err) {
+		return nil, err
+	}
+
+	verdicts := make([]Verdict, len(urls))
+	for i, url := range urls {
+		verdict := Verdict{Action: Allow}
+		if matches := set.Match(url); len(matches) > 0 {
+			verdict.Verified = true
+			verdict.Scope, verdict.Entry = matches[0].Scope, matches[0].Value
+		}
+		for _, cmd := range cmds[i] {
+			var data struct {
+				Rating  int    `json:"rating"`
+				Reports *int   `json:"reports"`
+				State   string `json:"state"`
+			}
+			if cmd.Err() != nil || json.Unmarshal([]byte(cmd.Val()), &data) != nil {
+				continue
+			}
+			// An admin reviewed the reports and found nothing wrong
+			if data.State == "rejected" {
+				continue
+			}
+			if data.Rating > verdict.Rating {
+				verdict.Rating = data.Rating
+				// Records from before reports were counted had at least one
+				verdict.Reports = 1
+				if data.Reports != nil {
+					verdict.Reports = *data.Reports
+				}
+			}
+		}
+
+		switch {
+		case verdict.Verified:
+			verdict.Action = cfg.VerifiedAction
+			verdict.Risk = RiskHigh
+		case verdict.Rating >= cfg.WarnThreshold:
+			verdict.Action = cfg.ReportedAction
+			verdict.Risk = RiskMedium
+		case verdict.Rating > 0:
+			verdict.Risk = RiskLow
+		default:
+			assessment := lexical.Score(url)
+			verdict.Lexical = &assessment
+			verdict.Risk = lexicalRisk(assessment.Score)
+		}
+		verdicts[i] = verdict
+	}
+	return verdicts, nil
+}
+
+// isReplyError reports whether err is an error reply from Redis, such as
+// WRONGTYPE, rather than a failure to reach it.
+func isReplyError(err error) bool {
+	var reply redis.Error
+	return errors.As(err, &reply) && err != redis.Nil
+}
@@ -0,0 +1,367 @@
+// Package scamrules resolves URLs against admin-registered scam entries of
+// every scope: exact URLs (the verified lists), whole domains, path
+// prefixes and restricted glob/regex patterns.
+//
+// Scoped entries live in the DB 3 hash "scam_entries", keyed by an ID
+// derived from their scope and value; the exact-URL entries come from the
+// verified index next to it.
+package scamrules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/verified"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/publicsuffix"
+)
+
+// EntriesKey is the DB 3 hash of scoped entries, ID to JSON Entry.
+// UpdatedKey holds the time of the last change to it, in unix nanoseconds.
+const (
+	EntriesKey = "scam_entries"
+	UpdatedKey = "scam_entries:updated_at"
+)
+
+// Scopes, from most to least specific.
+const (
+	ScopeURL     = "url"     // one canonical URL
+	ScopePrefix  = "prefix"  // a URL and everything below its path
+	ScopePattern = "pattern" // a glob or regular expression over canonical URLs
+	ScopeDomain  = "domain"  // a host and all of its subdomains
+)
+
+// Pattern kinds.
+const (
+	KindGlob  = "glob"
+	KindRegex = "regex"
+)
+
+const maxPatternLen = 256
+
+var (
+	ErrScope   = errors.New("scope must be one of url, prefix, pattern or domain")
+	ErrValue   = errors.New("value is not a valid URL or domain for this scope")
+	ErrPattern = errors.New("pattern is invalid or too broad")
+)
+
+// Entry is one scam entry. Value is stored normalized: a canonical URL for
+// url scope, a canonical key for prefix scope, a bare host for domain scope.
+// Patterns are matched against canonical keys ("host/path?query").
+type Entry struct {
+	ID        string    `json:"id"`
+	Scope     string    `json:"scope"`
+	Value     string    `json:"value"`
+	Kind      string    `json:"kind,omitempty"`
+	Admin     string    `json:"admin,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+var rank = map[string]int{ScopeURL: 0, ScopePrefix: 1, ScopePattern: 2, ScopeDomain: 3}
+
+// New validates and normalizes an entry for storage.
+func New(scope, value, kind, admin, reason string) (*Entry, error) {
+	e := &Entry{Scope: scope, Admin: admin, Reason: reason, CreatedAt: time.Now().UTC()}
+	value = strings.TrimSpace(value)
+	switch scope {
+	case ScopeURL:
+		url, err := canonical.URL(value)
+		if err != nil {
+			return nil, ErrValue
+		}
+		e.Value = url
+	case ScopePrefix:
+		if _, err := canonical.URL(value); err != nil {
+			return nil, ErrValue
+		}
+		e.Value = canonical.Key(value)
+	case ScopeDomain:
+		// "*.evil.com" is accepted as another spelling of "evil.com". A
+		// public suffix such as "co.uk" or "github.io" would cover every
+		// site registered under it, so only registrable domains and their
+		// subdomains are accepted
+		host := canonical.Host(strings.TrimPrefix(value, "*."))
+		if host == "" || !strings.Contains(host, ".") {
+			return nil, ErrValue
+		}
+		if _, err := publicsuffix.EffectiveTLDPlusOne(host); err != nil {
+			return nil, ErrValue
+		}
+		e.Value = host
+	case ScopePattern:
+		if kind == "" {
+			kind = KindGlob
+		}
+		e.Kind, e.Value = kind, value
+		if _, err := compile(kind, value); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrScope
+	}
+	e.ID = entryID(e)
+	return e, nil
+}
+
+// entryID derives an ID from what an entry matches, so registering the same
+// entry twice replaces it rather than adding a duplicate.
+func entryID(e *Entry) string {
+	sum := sha256.Sum256([]byte(e.Scope + "\x00" + e.Kind + "\x00" + e.Value))
+	return hex.EncodeToString(sum[:8])
+}
+
+// probes are ordinary URLs no legitimate pattern should match; a pattern
+// that does is too broad to register.
+var probes = []string{"example.com", "example.com/", "www.google.com/search?q=test", "a.b/c"}
+
+// compile turns a pattern into an anchored regular expression. Globs may
+// only use "*" (any run of characters), and not in the host except as a
+// leading "*." label. Regexes use RE2 syntax, so matching stays linear, and
+// are anchored at both ends. Either kind is refused if it matches one of the
+// probes.
+func compile(kind, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" || len(pattern) > maxPatternLen {
+		return nil, ErrPattern
+	}
+	var expr string
+	switch kind {
+	case KindGlob:
+		host, rest, _ := strings.Cut(strings.ToLower(pattern), "/")
+		hostExpr := ""
+		if suffix, ok := strings.CutPrefix(host, "*."); ok {
+			host = suffix
+			hostExpr = `(?:[^/?]+\.)?`
+		}
+		if host == "" || strings.Contains(host, "*") || !strings.Contains(host, ".") {
+			return nil, ErrPattern
+		}
+		expr = "^" + hostExpr + regexp.QuoteMeta(host)
+		if rest != "" {
+			parts := strings.Split(rest, "*")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+			expr += "/" + strings.Join(parts, ".*")
+		}
+		expr += "$"
+	case KindRegex:
+		expr = "^(?:" + pattern + ")$"
+	default:
+		return nil, ErrPattern
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, ErrPattern
+	}
+	for _, probe := range probes {
+		if re.MatchString(probe) {
+			return nil, ErrPattern
+		}
+	}
+	return re, nil
+}
+
+// Set is every entry, indexed for matching.
+type Set struct {
+	urls     map[string][]*Entry // by canonical key
+	prefixes map[string][]*Entry // by host
+	domains  map[string][]*Entry // by host
+	patterns []pattern
+	all      []*Entry
+}
+
+type pattern struct {
+	entry *Entry
+	re    *regexp.Regexp
+}
+
+func newSet(entries []*Entry) *Set {
+	s := &Set{
+		urls:     map[string][]*Entry{},
+		prefixes: map[string][]*Entry{},
+		domains:  map[string][]*Entry{},
+		all:      entries,
+	}
+	for _, e := range entries {
+		switch e.Scope {
+		case ScopeURL:
+			key := canonical.Key(e.Value)
+			s.urls[key] = append(s.urls[key], e)
+		case ScopePrefix:
+			host := canonical.Host(e.Value)
+			s.prefixes[host] = append(s.prefixes[host], e)
+		case ScopeDomain:
+			s.domains[e.Value] = append(s.domains[e.Value], e)
+		case ScopePattern:
+			if re, err := compile(e.Kind, e.Value); err == nil {
+				s.patterns = append(s.patterns, pattern{e, re})
+			}
+		}
+	}
+	return s
+}
+
+// Match returns every entry covering url, most specific first: exact URLs,
+// then path prefixes (longest first), then patterns, then domains (deepest
+// first).
+func (s *Set) Match(url string) []*Entry {
+	key := canonical.Key(url)
+	host := canonical.Host(url)
+	var out []*Entry
+	out = append(out, s.urls[key]...)
+	for _, e := range s.prefixes[host] {
+		if key == e.Value || strings.HasPrefix(key, e.Value+"/") || strings.HasPrefix(key, e.Value+"?") {
+			out = append(out, e)
+		}
+	}
+	for _, p := range s.patterns {
+		if p.re.MatchString(key) {
+			out = append(out, p.entry)
+		}
+	}
+	for h := host; h != ""; {
+		out = append(out, s.domains[h]...)
+		_, parent, ok := strings.Cut(h, ".")
+		if !ok {
+			break
+		}
+		h = parent
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if rank[out[i].Scope] != rank[out[j].Scope] {
+			return rank[out[i].Scope] < rank[out[j].Scope]
+		}
+		return len(out[i].Value) > len(out[j].Value)
+	})
+	return out
+}
+
+// Entries returns every entry, exact URLs included.
+func (s *Set) Entries() []*Entry {
+	return s.all
+}
+
+// Entries are spread across the verified index and the entries hash, so the
+// set is rebuilt at most every cacheTTL rather than on every redirect.
+const cacheTTL = 30 * time.Second
+
+var (
+	cacheMu      sync.Mutex
+	cache        *Set
+	cacheExpires time.Time
+)
+
+// Load returns the current set, from cache when it is fresh.
+func Load() (*Set, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if cache != nil && time.Now().Before(cacheExpires) {
+		return cache, nil
+	}
+	r := database.CreateClient(3)
+	defer r.Close()
+	entries, err := All(r)
+	if err != nil {
+		return nil, err
+	}
+	cache, cacheExpires = newSet(entries), time.Now().Add(cacheTTL)
+	return cache, nil
+}
+
+// Invalidate makes the next Load read the entries again.
+func Invalidate() {
+	cacheMu.Lock()
+	cache = nil
+	cacheMu.Unlock()
+}
+
+// All reads every entry, verified URLs included, bypassing the cache.
+func All(r *redis.Client) ([]*Entry, error) {
+	var entries []*Entry
+	records, err := verified.All(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		e := &Entry{Scope: ScopeURL, Value: rec.URL, Admin: rec.Admin, Reason: rec.Reason, CreatedAt: rec.VerifiedAt}
+		e.ID = entryID(e)
+		entries = append(entries, e)
+	}
+
+	stored, err := Scoped(r)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, stored...), nil
+}
+
+// Scoped returns the domain, prefix and pattern entries (and any URL entries
+// added through them), without the verified URLs.
+func Scoped(r *redis.Client) ([]*Entry, error) {
+	var entries []*Entry
+	stored, err := r.HGetAll(database.Ctx, EntriesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, val := range stored {
+		var e Entry
+		if err := json.Unmarshal([]byte(val), &e); err != nil {
+			continue
+		}
+		entries = append(entries, &e)
+	}
+	return entries, nil
+}
+
+// Add stores e, replacing an identical entry.
+func Add(r *redis.Client, e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	pipe := r.TxPipeline()
+	pipe.HSet(database.Ctx, EntriesKey, e.ID, data)
+	pipe.Set(database.Ctx, UpdatedKey, time.Now().UnixNano(), 0)
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return err
+	}
+	Invalidate()
+	return nil
+}
+
+// Remove deletes the entry with id, reporting whether there was one.
+func Remove(r *redis.Client, id string) (bool, error) {
+	n, err := r.HDel(database.Ctx, EntriesKey, id).Result()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		if err := r.Set(database.Ctx, UpdatedKey, time.Now().UnixNano(), 0).Err(); err != nil {
+			return true, err
+		}
+	}
+	Invalidate()
+	return n > 0, nil
+}
+
+// Updated returns when the scoped entries last changed, zero if never.
+func Updated(r *redis.Client) (time.Time, error) {
+	n, err := r.Get(database.Ctx, UpdatedKey).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, n).UTC(), nil
+}
@@ -0,0 +1,131 @@
+package scamrules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		scope, value, kind string
+		want               string // normalized value, empty when an error is expected
+		err                error
+	}{
+		{ScopeURL, "HTTPS://Evil.com/login?utm_source=x", "", "https://evil.com/login", nil},
+		{ScopeURL, "http://", "", "", ErrValue},
+		{ScopePrefix, "https://evil.com/phish/", "", "evil.com/phish", nil},
+		{ScopePrefix, "https://evil.com:80/phish", "", "evil.com/phish", nil},
+		{ScopePrefix, "", "", "", ErrValue},
+		{ScopeDomain, "Evil.com", "", "evil.com", nil},
+		{ScopeDomain, "*.evil.com", "", "evil.com", nil},
+		{ScopeDomain, "https://login.evil.co.uk/path", "", "login.evil.co.uk", nil},
+		{ScopeDomain, "phish.github.io", "", "phish.github.io", nil},
+		{ScopeDomain, "localhost", "", "", ErrValue},
+		{ScopeDomain, "com", "", "", ErrValue},
+		{ScopeDomain, "co.uk", "", "", ErrValue},
+		{ScopeDomain, "*.co.uk", "", "", ErrValue},
+		{ScopeDomain, "github.io", "", "", ErrValue},
+		{ScopePattern, "*.evil.com/login*", "", "*.evil.com/login*", nil},
+		{ScopePattern, `evil\.com/[a-z]+`, KindRegex, `evil\.com/[a-z]+`, nil},
+		{ScopePattern, "*", KindGlob, "", ErrPattern},
+		{ScopePattern, "*.com", KindGlob, "", ErrPattern},
+		{ScopePattern, ".*", KindRegex, "", ErrPattern},
+		{ScopePattern, "evil.com/(", KindRegex, "", ErrPattern},
+		{ScopePattern, "evil.com/a", "wildcard", "", ErrPattern},
+		{"host", "evil.com", "", "", ErrScope},
+	}
+	for _, tt := range tests {
+		e, err := New(tt.scope, tt.value, tt.kind, "admin@example.com", "")
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("New(%q, %q, %q) error = %v, want %v", tt.scope, tt.value, tt.kind, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("New(%q, %q, %q) returned error %v", tt.scope, tt.value, tt.kind, err)
+			continue
+		}
+		if e.Value != tt.want {
+			t.Errorf("New(%q, %q, %q).Value = %q, want %q", tt.scope, tt.value, tt.kind, e.Value, tt.want)
+		}
+	}
+}
+
+func TestNewSameEntrySameID(t *testing.T) {
+	a, err := New(ScopeDomain, "Evil.com", "", "one@example.com", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(ScopeDomain, "*.evil.com", "", "two@example.com", "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != b.ID {
+		t.Errorf("IDs differ for the same domain: %q and %q", a.ID, b.ID)
+	}
+	c, err := New(ScopePrefix, "evil.com", "", "one@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == c.ID {
+		t.Errorf("domain and prefix entries share ID %q", a.ID)
+	}
+}
+
+func mustNew(t *testing.T, scope, value, kind string) *Entry {
+	t.Helper()
+	e, err := New(scope, value, kind, "admin@example.com", "")
+	if err != nil {
+		t.Fatalf("New(%q, %q, %q): %v", scope, value, kind, err)
+	}
+	return e
+}
+
+func TestMatch(t *testing.T) {
+	url := mustNew(t, ScopeURL, "https://shop.example.org/pay?id=7", "")
+	prefix := mustNew(t, ScopePrefix, "shop.example.org/pay", "")
+	deepPrefix := mustNew(t, ScopePrefix, "shop.example.org/pay/card", "")
+	glob := mustNew(t, ScopePattern, "*.example.org/pay*", KindGlob)
+	regex := mustNew(t, ScopePattern, `[a-z]+\.phish\.net/.*`, KindRegex)
+	domain := mustNew(t, ScopeDomain, "example.org", "")
+	subdomain := mustNew(t, ScopeDomain, "shop.example.org", "")
+	set := newSet([]*Entry{url, prefix, deepPrefix, glob, regex, domain, subdomain})
+
+	tests := []struct {
+		url  string
+		want []*Entry
+	}{
+		{"http://Shop.Example.org/pay/?id=7&utm_source=x", []*Entry{url, prefix, glob, subdomain, domain}},
+		{"shop.example.org/pay/card/123", []*Entry{deepPrefix, prefix, glob, subdomain, domain}},
+		{"shop.example.org/payment", []*Entry{glob, subdomain, domain}},
+		{"shop.example.org/about", []*Entry{subdomain, domain}},
+		{"example.org", []*Entry{domain}},
+		{"https://shop.example.org:80/pay/card", []*Entry{deepPrefix, prefix, glob, subdomain, domain}},
+		{"www.login.phish.net/x", nil},
+		{"login.phish.net/x", []*Entry{regex}},
+		{"notexample.org/pay", nil},
+		{"example.org.evil.com/pay", nil},
+	}
+	for _, tt := range tests {
+		got := set.Match(tt.url)
+		if len(got) != len(tt.want) {
+			t.Errorf("Match(%q) = %v, want %v", tt.url, values(got), values(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Match(%q) = %v, want %v", tt.url, values(got), values(tt.want))
+				break
+			}
+		}
+	}
+}
+
+func values(entries []*Entry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Scope + ":" + e.Value
+	}
+	return out
+}
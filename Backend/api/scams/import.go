@@ -0,0 +1,19 @@
+package scams
+
+import (
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrImported = errors.New("URL was already imported from this feed")
+
+// Import adds rep as a report of url from an external feed. Each feed counts
+// once per URL: the summary record lists the feeds that reported it under
+// "feeds", and importing url from feed again fails with ErrImported, so
+// re-importing a file changes nothing. It reports whether the summary record
+// is new rather than merged into an existing one.
+func Import(r2 *redis.Client, url, feed string, rep Report) (bool, error) {
+	_, created, err := addReport(r2, url, rep, feed)
+	return created, err
+}
@@ -0,0 +1,150 @@
+// Package scams manages the community scam reports in DB 2, keyed by
+// canonical URL, with their per-user votes, and holds maintenance for them
+// and the admin-verified lists in DB 3.
+package scams
+
+import (
+	"encoding/json"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/go-redis/redis/v8"
+)
+
+// CanonicalizeReport summarises a Canonicalize run.
+type CanonicalizeReport struct {
+	Scanned       int `json:"reports_scanned"`
+	Rekeyed       int `json:"reports_rekeyed"`
+	Merged        int `json:"reports_merged"`
+	Skipped       int `json:"reports_skipped"`
+	AdminsUpdated int `json:"admins_updated"`
+}
+
+// Canonicalize rekeys every scam report in r2 (DB 2) under its canonical URL,
+// merging reports that turn out to be the same URL: ratings and report counts
+// are summed and the description of the highest-rated report is kept.
+// Verified lists in r3 (DB 3) are rewritten in canonical form without
+// duplicates. It is safe to run more than once.
+func Canonicalize(r2, r3 *redis.Client) (CanonicalizeReport, error) {
+	var report CanonicalizeReport
+
+	// Group first: rewriting while scanning could visit a key twice
+	groups := make(map[string][]string)
+	iter := r2.Scan(database.Ctx, 0, "*", 500).Iterator()
+	for iter.Next(database.Ctx) {
+		key := iter.Val()
+		if !IsReportKey(key) {
+			continue
+		}
+		report.Scanned++
+		groups[canonical.Key(key)] = append(groups[canonical.Key(key)], key)
+	}
+	if err := iter.Err(); err != nil {
+		return report, err
+	}
+
+	for target, keys := range groups {
+		if len(keys) == 1 && keys[0] == target {
+			continue
+		}
+		merged := map[string]interface{}{}
+		var rating, reports, best float64
+		var sources []string
+		for _, key := range keys {
+			val, err := r2.Get(database.Ctx, key).Result()
+			if err != nil {
+				report.Skipped++
+				continue
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(val), &data); err != nil {
+				report.Skipped++
+				continue
+			}
+			n, _ := data["rating"].(float64)
+			rating += n
+			if count, ok := data["reports"].(float64); ok {
+				reports += count
+			} else {
+				reports++
+			}
+			if len(sources) == 0 || n > best {
+				best = n
+				for k, v := range data {
+					merged[k] = v
+				}
+			}
+			sources = append(sources, key)
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		merged["rating"] = rating
+		merged["reports"] = reports
+		if url, err := canonical.URL(target); err == nil {
+			merged["url"] = url
+		}
+		out, err := json.Marshal(merged)
+		if err != nil {
+			return report, err
+		}
+		pipe := r2.TxPipeline()
+		for _, key := range sources {
+			if key != target {
+				pipe.Del(database.Ctx, key)
+			}
+		}
+		pipe.Set(database.Ctx, target, out, 0)
+		if _, err := pipe.Exec(database.Ctx); err != nil {
+			return report, err
+		}
+		if len(sources) > 1 {
+			report.Merged += len(sources) - 1
+		} else {
+			report.Rekeyed++
+		}
+	}
+
+	iter = r3.Scan(database.Ctx, 0, "admin:*", 100).Iterator()
+	for iter.Next(database.Ctx) {
+		key := iter.Val()
+		val, err := r3.Get(database.Ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		var admin models.Admin
+		if err := json.Unmarshal([]byte(val), &admin); err != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		urls := []string{}
+		changed := false
+		for _, raw := range admin.VerifiedURLs {
+			url, err := canonical.URL(raw)
+			if err != nil {
+				url = raw
+			}
+			if seen[canonical.Key(url)] {
+				changed = true
+				continue
+			}
+			seen[canonical.Key(url)] = true
+			changed = changed || url != raw
+			urls = append(urls, url)
+		}
+		if !changed {
+			continue
+		}
+		admin.VerifiedURLs = urls
+		out, err := json.Marshal(admin)
+		if err != nil {
+			return report, err
+		}
+		if err := r3.Set(database.Ctx, key, out, 0).Err(); err != nil {
+			return report, err
+		}
+		report.AdminsUpdated++
+	}
+	return report, iter.Err()
+}
@@ -0,0 +1,215 @@
+package scams
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Report categories.
+var Categories = []string{"phishing", "fraud", "malware", "impersonation", "spam", "other"}
+
+const (
+	maxDescriptionLen = 2000
+	maxSourceLen      = 500
+	maxHistory        = 500 // reports kept per URL
+)
+
+var (
+	ErrCategory    = errors.New("category must be one of phishing, fraud, malware, impersonation, spam or other")
+	ErrReportLimit = errors.New("description or source is too long")
+	ErrReported    = errors.New("you have already reported this URL")
+)
+
+// The latest maxHistory reports on a URL are kept, newest first, in a DB 2
+// list next to its summary record.
+const historyPrefix = "reports:"
+
+func historyKey(key string) string {
+	return historyPrefix + key
+}
+
+// Each URL's reporters live in a DB 2 hash next to it, email to the time of
+// their report, so one person can only add one report to the rating.
+const reportersPrefix = "reporters:"
+
+func reportersKey(key string) string {
+	return reportersPrefix + key
+}
+
+// Report is one user's report of a URL.
+type Report struct {
+	ID          string    `json:"id"`
+	Reporter    string    `json:"reporter,omitempty"`
+	At          time.Time `json:"at"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Source      string    `json:"source,omitempty"`
+}
+
+// AddReport stores rep in url's history and updates the summary record:
+// the report and rating counts, the count per category, and the latest
+// description. It returns the updated summary, ErrReported if rep's reporter
+// has reported url before, or ErrCooldown while a rejection of url is
+// cooling down.
+func AddReport(r2 *redis.Client, url string, rep Report) (map[string]interface{}, error) {
+	data, _, err := addReport(r2, url, rep, "")
+	return data, err
+}
+
+// addReport does the work of AddReport and Import. A non-empty feed tags the
+// summary record with it, refusing with ErrImported if it already is. It
+// also reports whether the summary record is new.
+func addReport(r2 *redis.Client, url string, rep Report, feed string) (map[string]interface{}, bool, error) {
+	rep.Category = strings.ToLower(strings.TrimSpace(rep.Category))
+	if rep.Category == "" {
+		rep.Category = "other"
+	}
+	valid := false
+	for _, c := range Categories {
+		valid = valid || c == rep.Category
+	}
+	if !valid {
+		return nil, false, ErrCategory
+	}
+	if len(rep.Description) > maxDescriptionLen || len(rep.Source) > maxSourceLen {
+		return nil, false, ErrReportLimit
+	}
+	canonicalURL, err := canonical.URL(url)
+	if err != nil {
+		return nil, false, err
+	}
+	rep.ID = uuid.New().String()
+	rep.At = time.Now().UTC()
+	entry, err := json.Marshal(rep)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := canonical.Key(canonicalURL)
+	reporters := reportersKey(key)
+	var data map[string]interface{}
+	var created bool
+	txf := func(tx *redis.Tx) error {
+		val, err := tx.Get(database.Ctx, key).Result()
+		data = map[string]interface{}{"url": canonicalURL}
+		created = err == redis.Nil
+		switch {
+		case err == redis.Nil:
+			data["first_reported_at"] = rep.At
+		case err != nil:
+			return err
+		default:
+			if err := json.Unmarshal([]byte(val), &data); err != nil {
+				return err
+			}
+		}
+
+		if n, err := tx.Exists(database.Ctx, cooldownKey(key)).Result(); err != nil {
+			return err
+		} else if n > 0 {
+			return ErrCooldown
+		}
+		if rep.Reporter != "" {
+			if seen, err := tx.HExists(database.Ctx, reporters, rep.Reporter).Result(); err != nil {
+				return err
+			} else if seen {
+				return ErrReported
+			}
+		}
+		if feed != "" {
+			feeds, _ := data["feeds"].(map[string]interface{})
+			if feeds == nil {
+				feeds = map[string]interface{}{}
+			}
+			if _, ok := feeds[feed]; ok {
+				return ErrImported
+			}
+			feeds[feed] = rep.At
+			data["feeds"] = feeds
+		}
+		// A new report reopens URLs whose earlier reports expired or whose
+		// rejection has cooled down
+		if state := State(data); state == StateExpired || state == StateRejected {
+			setState(data, StateReported, "", "Reported again")
+		}
+
+		rating, _ := data["rating"].(float64)
+		// Reports are counted apart from votes; records from before that had one
+		reports, ok := data["reports"].(float64)
+		if !ok && rating > 0 {
+			reports = 1
+		}
+		categories, _ := data["categories"].(map[string]interface{})
+		if categories == nil {
+			categories = map[string]interface{}{}
+		}
+		count, _ := categories[rep.Category].(float64)
+		categories[rep.Category] = count + 1
+		data["rating"] = rating + 1
+		data["reports"] = reports + 1
+		data["categories"] = categories
+		data["description"] = rep.Description
+		data["last_reported_at"] = rep.At
+
+		out, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(database.Ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(database.Ctx, key, out, 0)
+			queue(pipe, key, data)
+			pipe.LPush(database.Ctx, historyKey(key), entry)
+			pipe.LTrim(database.Ctx, historyKey(key), 0, maxHistory-1)
+			if rep.Reporter != "" {
+				pipe.HSet(database.Ctx, reporters, rep.Reporter, rep.At.Unix())
+			}
+			return nil
+		})
+		return err
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		err := r2.Watch(database.Ctx, txf, key, cooldownKey(key), reporters)
+		if err != redis.TxFailedErr {
+			return data, created, err
+		}
+	}
+	return nil, false, redis.TxFailedErr
+}
+
+// FlagLookalike marks the report for url as imitating the protected brand
+// domain, and how (see package brands).
+func FlagLookalike(r2 *redis.Client, url, brand, method string) (map[string]interface{}, error) {
+	return modify(r2, url, func(data map[string]interface{}, _ redis.Pipeliner) error {
+		data["lookalike_of"] = brand
+		data["lookalike_method"] = method
+		return nil
+	})
+}
+
+// Timeline returns up to limit of url's reports, newest first, starting
+// offset reports in, along with how many there are in all.
+func Timeline(r2 *redis.Client, url string, offset, limit int64) ([]Report, int64, error) {
+	key := historyKey(canonical.Key(url))
+	pipe := r2.Pipeline()
+	total := pipe.LLen(database.Ctx, key)
+	page := pipe.LRange(database.Ctx, key, offset, offset+limit-1)
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return nil, 0, err
+	}
+	reports := make([]Report, 0, len(page.Val()))
+	for _, val := range page.Val() {
+		var rep Report
+		if err := json.Unmarshal([]byte(val), &rep); err != nil {
+			continue
+		}
+		reports = append(reports, rep)
+	}
+	return reports, total.Val(), nil
+}
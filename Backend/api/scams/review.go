@@ -0,0 +1,335 @@
+package scams
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/go-redis/redis/v8"
+)
+
+// Review states. A report starts out reported; an admin claims it for
+// review and then verifies or rejects it. Reports nobody has added to for
+// REPORT_EXPIRY_DAYS expire instead of waiting in the queue forever.
+const (
+	StateReported    = "reported"
+	StateUnderReview = "under_review"
+	StateVerified    = "verified"
+	StateRejected    = "rejected"
+	StateExpired     = "expired"
+)
+
+var (
+	ErrClaimed  = errors.New("report is being reviewed by another admin")
+	ErrResolved = errors.New("report has already been verified or rejected")
+	ErrCooldown = errors.New("this URL was reviewed and rejected recently and can't be reported again yet")
+)
+
+// QueueKey is the DB 2 sorted set of reports waiting for review, scored by
+// rating.
+const QueueKey = "queue:pending"
+
+// Rejected URLs can't be reported again while their cooldown key exists.
+const cooldownPrefix = "cooldown:"
+
+func cooldownKey(key string) string {
+	return cooldownPrefix + key
+}
+
+// A claim older than claimTimeout no longer keeps other admins out.
+const claimTimeout = time.Hour
+
+// ReviewConfig is read from the environment: REJECT_COOLDOWN_DAYS (default
+// 30) and REPORT_EXPIRY_DAYS (default 90).
+type ReviewConfig struct {
+	RejectCooldown time.Duration
+	ReportExpiry   time.Duration
+}
+
+func ReviewConfigFromEnv() ReviewConfig {
+	return ReviewConfig{
+		RejectCooldown: envDays("REJECT_COOLDOWN_DAYS", 30),
+		ReportExpiry:   envDays("REPORT_EXPIRY_DAYS", 90),
+	}
+}
+
+func envDays(name string, fallback int) time.Duration {
+	days := fallback
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		days = n
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// State returns a summary record's review state. Records from before states
+// existed are reported.
+func State(data map[string]interface{}) string {
+	if state, ok := data["state"].(string); ok && state != "" {
+		return state
+	}
+	return StateReported
+}
+
+// pending reports whether a report belongs in the review queue.
+func pending(data map[string]interface{}) bool {
+	state := State(data)
+	return state == StateReported || state == StateUnderReview
+}
+
+// queue keeps a report's place in the review queue in step with its record.
+func queue(pipe redis.Pipeliner, key string, data map[string]interface{}) {
+	if pending(data) {
+		rating, _ := data["rating"].(float64)
+		pipe.ZAdd(database.Ctx, QueueKey, &redis.Z{Score: rating, Member: key})
+	} else {
+		pipe.ZRem(database.Ctx, QueueKey, key)
+	}
+}
+
+func setState(data map[string]interface{}, state, admin, reason string) {
+	data["state"] = state
+	data["state_by"] = admin
+	data["state_reason"] = reason
+	data["state_changed_at"] = time.Now().UTC()
+	if state != StateUnderReview {
+		delete(data, "claimed_by")
+		delete(data, "claimed_at")
+	}
+}
+
+// modify applies fn to the summary record for url and saves the result,
+// keeping the queue in step, retrying if the record changes underneath.
+// fn may queue extra commands on pipe to run in the same transaction.
+func modify(r2 *redis.Client, url string, fn func(data map[string]interface{}, pipe redis.Pipeliner) error) (map[string]interface{}, error) {
+	return modifyKey(r2, canonical.Key(url), fn)
+}
+
+// modifyKey is modify for the record stored under key, used as is. Keys
+// read back from the database must not go through canonical.Key again.
+func modifyKey(r2 *redis.Client, key string, fn func(data map[string]interface{}, pipe redis.Pipeliner) error) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	txf := func(tx *redis.Tx) error {
+		val, err := tx.Get(database.Ctx, key).Result()
+		if err == redis.Nil {
+			return ErrNoReport
+		}
+		if err != nil {
+			return err
+		}
+		data = nil
+		if err := json.Unmarshal([]byte(val), &data); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(database.Ctx, func(pipe redis.Pipeliner) error {
+			if err := fn(data, pipe); err != nil {
+				return err
+			}
+			out, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			pipe.Set(database.Ctx, key, out, 0)
+			queue(pipe, key, data)
+			return nil
+		})
+		return err
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		err := r2.Watch(database.Ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return data, err
+		}
+	}
+	return nil, redis.TxFailedErr
+}
+
+// Claim marks the report for url as under review by admin. An admin can
+// re-claim their own report, or take over a claim that has gone stale.
+func Claim(r2 *redis.Client, url, admin string) (map[string]interface{}, error) {
+	return modify(r2, url, func(data map[string]interface{}, _ redis.Pipeliner) error {
+		switch State(data) {
+		case StateVerified, StateRejected:
+			return ErrResolved
+		case StateUnderReview:
+			by, _ := data["claimed_by"].(string)
+			at, _ := time.Parse(time.RFC3339Nano, stringField(data, "claimed_at"))
+			if by != admin && time.Since(at) < claimTimeout {
+				return ErrClaimed
+			}
+		}
+		setState(data, StateUnderReview, admin, "")
+		data["claimed_by"] = admin
+		data["claimed_at"] = time.Now().UTC()
+		return nil
+	})
+}
+
+// Verify marks the report for url as verified. It only records the
+// decision; putting the URL on the verified list is up to the caller.
+func Verify(r2 *redis.Client, url, admin, reason string) (map[string]interface{}, error) {
+	return modify(r2, url, func(data map[string]interface{}, _ redis.Pipeliner) error {
+		if err := checkClaim(data, admin); err != nil {
+			return err
+		}
+		setState(data, StateVerified, admin, reason)
+		return nil
+	})
+}
+
+// MarkVerified records a verification made outside the review flow, such
+// as straight onto an admin's verified list, whoever has the report claimed.
+func MarkVerified(r2 *redis.Client, url, admin, reason string) (map[string]interface{}, error) {
+	return modify(r2, url, func(data map[string]interface{}, _ redis.Pipeliner) error {
+		setState(data, StateVerified, admin, reason)
+		return nil
+	})
+}
+
+// Reopen puts a report back in the queue, for when its verification is
+// revoked.
+func Reopen(r2 *redis.Client, url, admin, reason string) (map[string]interface{}, error) {
+	return modify(r2, url, func(data map[string]interface{}, _ redis.Pipeliner) error {
+		setState(data, StateReported, admin, reason)
+		return nil
+	})
+}
+
+// Reject marks the report for url as rejected and refuses new reports of it
+// for the configured cooldown. Verified reports can't be rejected.
+func Reject(r2 *redis.Client, url, admin, reason string) (map[string]interface{}, error) {
+	cfg := ReviewConfigFromEnv()
+	return modify(r2, url, func(data map[string]interface{}, pipe redis.Pipeliner) error {
+		if State(data) == StateVerified {
+			return ErrResolved
+		}
+		if err := checkClaim(data, admin); err != nil {
+			return err
+		}
+		setState(data, StateRejected, admin, reason)
+		pipe.Set(database.Ctx, cooldownKey(canonical.Key(url)), admin, cfg.RejectCooldown)
+		return nil
+	})
+}
+
+// checkClaim refuses decisions on reports another admin is still reviewing.
+func checkClaim(data map[string]interface{}, admin string) error {
+	if State(data) != StateUnderReview {
+		return nil
+	}
+	by, _ := data["claimed_by"].(string)
+	at, _ := time.Parse(time.RFC3339Nano, stringField(data, "claimed_at"))
+	if by != admin && time.Since(at) < claimTimeout {
+		return ErrClaimed
+	}
+	return nil
+}
+
+func stringField(data map[string]interface{}, name string) string {
+	s, _ := data[name].(string)
+	return s
+}
+
+// QueueEntry is one report waiting for review.
+type QueueEntry struct {
+	Key     string                 `json:"key"`
+	Rating  float64                `json:"rating"`
+	Summary map[string]interface{} `json:"summary"`
+}
+
+// Queue expires at most this many pages' worth of quiet reports per call,
+// so a page always comes back even if entries refuse to leave the queue.
+const maxQueuePasses = 10
+
+// Queue returns up to limit pending reports, highest rated first, starting
+// offset entries in, and how many are pending. Reports that have gone
+// quiet for longer than the configured expiry are expired on the way.
+func Queue(r2 *redis.Client, offset, limit int64) ([]QueueEntry, int64, error) {
+	cfg := ReviewConfigFromEnv()
+	entries := []QueueEntry{}
+	for pass := 0; pass < maxQueuePasses; pass++ {
+		members, err := r2.ZRevRangeWithScores(database.Ctx, QueueKey, offset, offset+limit-1).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		expired := 0
+		for _, m := range members {
+			key := m.Member.(string)
+			val, err := r2.Get(database.Ctx, key).Result()
+			if err == redis.Nil {
+				r2.ZRem(database.Ctx, QueueKey, key)
+				expired++
+				continue
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(val), &data); err != nil {
+				continue
+			}
+			last, err := time.Parse(time.RFC3339Nano, stringField(data, "last_reported_at"))
+			if err == nil && time.Since(last) > cfg.ReportExpiry && State(data) == StateReported {
+				// The member is the stored key, so it is used as is
+				updated, err := modifyKey(r2, key, func(data map[string]interface{}, _ redis.Pipeliner) error {
+					if State(data) == StateReported {
+						setState(data, StateExpired, "", "No new reports")
+					}
+					return nil
+				})
+				switch {
+				case err == ErrNoReport:
+					r2.ZRem(database.Ctx, QueueKey, key)
+					expired++
+					continue
+				case err != nil:
+					return nil, 0, err
+				case !pending(updated):
+					// modifyKey took it out of the queue
+					expired++
+					continue
+				}
+				// Reported again or claimed in the meantime: it stays
+				data = updated
+			}
+			entries = append(entries, QueueEntry{Key: key, Rating: m.Score, Summary: data})
+		}
+		// Expired entries left the queue; fill the page from what moved up
+		if expired == 0 || len(members) < int(limit) || pass == maxQueuePasses-1 {
+			break
+		}
+		entries = entries[:0]
+	}
+	total, err := r2.ZCard(database.Ctx, QueueKey).Result()
+	return entries, total, err
+}
+
+// RebuildQueue adds every pending report to the review queue, for reports
+// written before the queue existed. It is safe to run more than once.
+func RebuildQueue(r2 *redis.Client) (int, error) {
+	queued := 0
+	iter := r2.Scan(database.Ctx, 0, "*", 500).Iterator()
+	for iter.Next(database.Ctx) {
+		key := iter.Val()
+		if !IsReportKey(key) {
+			continue
+		}
+		val, err := r2.Get(database.Ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(val), &data); err != nil || !pending(data) {
+			continue
+		}
+		rating, _ := data["rating"].(float64)
+		if err := r2.ZAdd(database.Ctx, QueueKey, &redis.Z{Score: rating, Member: key}).Err(); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, iter.Err()
+}
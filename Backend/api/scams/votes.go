@@ -0,0 +1,141 @@
+package scams
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/go-redis/redis/v8"
+)
+
+// Votes a user can cast on a report. Retract removes their vote.
+const (
+	VoteUp      = "up"   // it is a scam
+	VoteDown    = "down" // it is legitimate
+	VoteRetract = "retract"
+)
+
+var (
+	ErrNoReport = errors.New("URL not found or no scam data available")
+	ErrVote     = errors.New("vote must be up, down or retract")
+)
+
+// Each report's voters live in a DB 2 hash next to it, email to vote.
+const votesPrefix = "votes:"
+
+func votesKey(key string) string {
+	return votesPrefix + key
+}
+
+// IsReportKey reports whether a DB 2 key holds a report rather than data
+// kept alongside reports.
+func IsReportKey(key string) bool {
+	for _, prefix := range []string{votesPrefix, historyPrefix, reportersPrefix, cooldownPrefix, "queue:"} {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Tally is a report's vote counts after a vote. Score is Up minus Down;
+// Rating is what safety checks use: reports plus the net score (plus any
+// anonymous votes counted before votes were per user).
+type Tally struct {
+	Vote   string  `json:"vote"` // the user's vote now, empty when retracted
+	Up     int64   `json:"up"`
+	Down   int64   `json:"down"`
+	Score  int64   `json:"score"`
+	Rating float64 `json:"rating"`
+}
+
+func weight(vote string) float64 {
+	switch vote {
+	case VoteUp:
+		return 1
+	case VoteDown:
+		return -1
+	}
+	return 0
+}
+
+// CastVote records email's vote on the report for url, replacing any vote
+// they cast before, and adjusts the report's counts to match. The vote and
+// the counts change together or not at all.
+func CastVote(r2 *redis.Client, url, email, vote string) (*Tally, error) {
+	if vote != VoteUp && vote != VoteDown && vote != VoteRetract {
+		return nil, ErrVote
+	}
+	key := canonical.Key(url)
+	votes := votesKey(key)
+	var tally *Tally
+	txf := func(tx *redis.Tx) error {
+		val, err := tx.Get(database.Ctx, key).Result()
+		if err == redis.Nil {
+			return ErrNoReport
+		}
+		if err != nil {
+			return err
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(val), &data); err != nil {
+			return err
+		}
+		previous, err := tx.HGet(database.Ctx, votes, email).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		up, _ := data["up"].(float64)
+		down, _ := data["down"].(float64)
+		rating, _ := data["rating"].(float64)
+		switch previous {
+		case VoteUp:
+			up--
+		case VoteDown:
+			down--
+		}
+		switch vote {
+		case VoteUp:
+			up++
+		case VoteDown:
+			down++
+		}
+		rating += weight(vote) - weight(previous)
+		data["up"], data["down"], data["score"], data["rating"] = up, down, up-down, rating
+
+		out, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(database.Ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(database.Ctx, key, out, 0)
+			queue(pipe, key, data)
+			if vote == VoteRetract {
+				pipe.HDel(database.Ctx, votes, email)
+			} else {
+				pipe.HSet(database.Ctx, votes, email, vote)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		tally = &Tally{Up: int64(up), Down: int64(down), Score: int64(up - down), Rating: rating}
+		if vote != VoteRetract {
+			tally.Vote = vote
+		}
+		return nil
+	}
+
+	// Retry when another vote on the same report lands in between
+	for attempt := 0; attempt < 5; attempt++ {
+		err := r2.Watch(database.Ctx, txf, key, votes)
+		if err != redis.TxFailedErr {
+			return tally, err
+		}
+	}
+	return nil, redis.TxFailedErr
+}
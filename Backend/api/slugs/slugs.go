@@ -0,0 +1,209 @@
+// Package slugs decides which custom short codes users may claim.
+//
+// A slug must match the configured pattern, must not shadow one of the
+// router's own paths, must not be on the reserved list (env plus a set in
+// DB 3 that admins manage at runtime) and must not contain a blocked word.
+package slugs
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/gin-gonic/gin"
+)
+
+const DefaultPattern = `^[A-Za-z0-9][A-Za-z0-9_-]{2,31}$`
+
+// ReservedKey is the DB 3 set holding reserved words added through the API.
+const ReservedKey = "reserved_slugs"
+
+var (
+	ErrFormat   = errors.New("short has characters or a length that are not allowed")
+	ErrReserved = errors.New("this short is reserved")
+	ErrBlocked  = errors.New("this short contains a blocked word")
+)
+
+// builtinReserved are words we never hand out, whatever the configuration.
+var builtinReserved = []string{
+	"admin", "api", "app", "assets", "auth", "check", "dashboard", "docs",
+	"favicon.ico", "health", "help", "login", "logout", "robots.txt", "signup",
+	"static", "status", "support", "www",
+}
+
+var (
+	mu         sync.RWMutex
+	routeWords = map[string]bool{}
+
+	loadOnce  sync.Once
+	pattern   *regexp.Regexp
+	envWords  map[string]bool
+	blocklist []string
+)
+
+// RegisterRoutes reserves the first path segment of every static route, so
+// no short link can shadow them.
+func RegisterRoutes(routes gin.RoutesInfo) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, route := range routes {
+		first := strings.SplitN(strings.TrimPrefix(route.Path, "/"), "/", 2)[0]
+		if first != "" && !strings.HasPrefix(first, ":") && !strings.HasPrefix(first, "*") {
+			routeWords[strings.ToLower(first)] = true
+		}
+	}
+}
+
+// load reads SLUG_PATTERN, RESERVED_SLUGS (comma separated), BLOCKED_SLUGS
+// (comma separated) and BLOCKED_SLUGS_FILE (one word per line).
+func load() {
+	loadOnce.Do(func() {
+		pattern = regexp.MustCompile(DefaultPattern)
+		if p := os.Getenv("SLUG_PATTERN"); p != "" {
+			if re, err := regexp.Compile(p); err == nil {
+				pattern = re
+			} else {
+				log.Println("slugs: invalid SLUG_PATTERN, using default:", err)
+			}
+		}
+
+		envWords = map[string]bool{}
+		for _, w := range append(builtinReserved, splitList(os.Getenv("RESERVED_SLUGS"))...) {
+			envWords[strings.ToLower(w)] = true
+		}
+
+		for _, w := range splitList(os.Getenv("BLOCKED_SLUGS")) {
+			blocklist = append(blocklist, normalize(w))
+		}
+		if path := os.Getenv("BLOCKED_SLUGS_FILE"); path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Println("slugs: cannot read BLOCKED_SLUGS_FILE:", err)
+				return
+			}
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if w := strings.TrimSpace(scanner.Text()); w != "" && !strings.HasPrefix(w, "#") {
+					blocklist = append(blocklist, normalize(w))
+				}
+			}
+		}
+	})
+}
+
+// Check reports why slug can't be used as a custom short, or nil if it can.
+func Check(slug string) error {
+	load()
+	if !pattern.MatchString(slug) {
+		return ErrFormat
+	}
+	return Allowed(slug)
+}
+
+// Allowed applies the reserved and blocked lists but not the format rules,
+// for codes the server generates itself.
+func Allowed(slug string) error {
+	load()
+	lower := strings.ToLower(slug)
+	if reservedLocally(lower) {
+		return ErrReserved
+	}
+	r := database.CreateClient(3)
+	defer r.Close()
+	if reserved, err := r.SIsMember(database.Ctx, ReservedKey, lower).Result(); err == nil && reserved {
+		return ErrReserved
+	}
+	if blocked(slug) {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// reservedLocally reports whether the lower-cased slug is a route or a
+// configured reserved word.
+func reservedLocally(lower string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return routeWords[lower] || envWords[lower]
+}
+
+// blocked reports whether slug, normalized, contains a blocked word.
+func blocked(slug string) bool {
+	norm := normalize(slug)
+	for _, word := range blocklist {
+		if word != "" && strings.Contains(norm, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reserved lists every reserved word and where it comes from.
+func Reserved() (map[string][]string, error) {
+	load()
+	r := database.CreateClient(3)
+	defer r.Close()
+	runtime, err := r.SMembers(database.Ctx, ReservedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return map[string][]string{
+		"routes":  keys(routeWords),
+		"config":  keys(envWords),
+		"runtime": runtime,
+	}, nil
+}
+
+// normalize folds case, separators and common look-alike digits so that
+// "B-4-d_W0rd" is caught by a blocklist entry for "badword".
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch r {
+		case '-', '_', '.', ' ':
+			continue
+		case '0':
+			r = 'o'
+		case '1', '!':
+			r = 'i'
+		case '3':
+			r = 'e'
+		case '4', '@':
+			r = 'a'
+		case '5', '$':
+			r = 's'
+		case '7':
+			r = 't'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func keys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
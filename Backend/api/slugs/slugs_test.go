@@ -0,0 +1,135 @@
+package slugs
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"badword", "badword"},
+		{"BadWord", "badword"},
+		{"B-4-d_W0rd", "badword"},
+		{"b.a d", "bad"},
+		{"5c@m", "scam"},
+		{"$c4m", "scam"},
+		{"1nv3st", "invest"},
+		{"!t3m", "item"},
+		{"7057", "tost"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultPattern(t *testing.T) {
+	re := regexp.MustCompile(DefaultPattern)
+	tests := []struct {
+		slug string
+		ok   bool
+	}{
+		{"abc", true},
+		{"My-Link_2024", true},
+		{"0ab", true},
+		{"a234567890123456789012345678901", true},    // 31 characters
+		{"a2345678901234567890123456789012", true},   // 32
+		{"a23456789012345678901234567890123", false}, // 33
+		{"ab", false},
+		{"", false},
+		{"-abc", false},
+		{"_abc", false},
+		{"ab.c", false},
+		{"ab c", false},
+		{"ab/c", false},
+		{"abc\n", false},
+		{"äbc", false},
+	}
+	for _, tt := range tests {
+		if got := re.MatchString(tt.slug); got != tt.ok {
+			t.Errorf("DefaultPattern matches %q = %v, want %v", tt.slug, got, tt.ok)
+		}
+	}
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	load()
+	RegisterRoutes(gin.RoutesInfo{
+		{Method: "GET", Path: "/:shortID"},
+		{Method: "POST", Path: "/:shortID/continue"},
+		{Method: "GET", Path: "/api/v1/links"},
+		{Method: "GET", Path: "/Health"},
+		{Method: "GET", Path: "/metrics/"},
+		{Method: "GET", Path: "/*filepath"},
+		{Method: "GET", Path: "/"},
+	})
+	tests := []struct {
+		slug     string
+		reserved bool
+	}{
+		{"api", true},
+		{"health", true},
+		{"metrics", true},
+		{"admin", true}, // built in
+		{"robots.txt", true},
+		{"v1", false}, // only the first segment is taken
+		{"links", false},
+		{":shortid", false},
+		{"*filepath", false},
+		{"continue", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := reservedLocally(tt.slug); got != tt.reserved {
+			t.Errorf("reservedLocally(%q) = %v, want %v", tt.slug, got, tt.reserved)
+		}
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	load()
+	saved := blocklist
+	defer func() { blocklist = saved }()
+	blocklist = []string{normalize("scam"), normalize("bad-word"), ""}
+
+	tests := []struct {
+		slug    string
+		blocked bool
+	}{
+		{"scam", true},
+		{"free-5c4m-now", true},
+		{"BAD_W0RD", true},
+		{"notbadwords", true},
+		{"scan", false},
+		{"bad", false},
+		{"hello", false},
+	}
+	for _, tt := range tests {
+		if got := blocked(tt.slug); got != tt.blocked {
+			t.Errorf("blocked(%q) = %v, want %v", tt.slug, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	// Format errors come before any list is consulted
+	for _, slug := range []string{"ab", "-abc", "has space", "a/b"} {
+		if err := Check(slug); err != ErrFormat {
+			t.Errorf("Check(%q) = %v, want ErrFormat", slug, err)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" a, b ,,c,")
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("splitList = %q", got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList(\"\") = %q, want nil", got)
+	}
+}
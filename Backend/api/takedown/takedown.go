@@ -0,0 +1,228 @@
+// Package takedown disables existing short links whose destination has been
+// verified as a scam, and undoes that when a verification turns out wrong.
+//
+// Each run is recorded in DB 0 as a "takedown:<id>" hash with the set of
+// links it disabled in "takedown:<id>:links"; "takedown:index" orders them by
+// time. Every key is namespaced, since bare keys in DB 0 are short links.
+package takedown
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/notify"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Scopes a takedown can cover.
+const (
+	ScopeURL    = "url"    // links to exactly this URL
+	ScopeDomain = "domain" // links to anywhere on the URL's host
+)
+
+const indexKey = "takedown:index"
+
+// legacyIndexKey is where the index lived before it was namespaced, which
+// left it open to being claimed as a custom short.
+const legacyIndexKey = "takedowns"
+
+// Record describes one takedown run.
+type Record struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Scope     string    `json:"scope"`
+	Admin     string    `json:"admin"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	UndoneAt  time.Time `json:"undone_at,omitzero"`
+	UndoneBy  string    `json:"undone_by,omitempty"`
+	Links     []string  `json:"links"`
+}
+
+func recordKey(id string) string { return "takedown:" + id }
+func linksKey(id string) string  { return "takedown:" + id + ":links" }
+
+// Start runs a takedown in the background so the verifying request doesn't
+// wait for it.
+func Start(url, scope, admin, reason string) {
+	go func() {
+		rec, err := Run(url, scope, admin, reason)
+		if err != nil {
+			log.Println("takedown of", url, "failed:", err)
+			return
+		}
+		log.Printf("takedown %s disabled %d link(s) pointing at %s", rec.ID, len(rec.Links), url)
+	}()
+}
+
+// Run disables every live link matching url within scope, records why, and
+// notifies the owners.
+func Run(url, scope, admin, reason string) (*Record, error) {
+	if scope != ScopeDomain {
+		scope = ScopeURL
+	}
+	if reason == "" {
+		reason = "Destination verified as a scam"
+	}
+	r := database.CreateClient(0)
+	defer r.Close()
+
+	var matches []*models.Link
+	var err error
+	if scope == ScopeDomain {
+		matches, err = links.ByHost(r, links.Host(url))
+	} else {
+		matches, err = links.ByDestination(r, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{
+		ID:        uuid.New().String(),
+		URL:       url,
+		Scope:     scope,
+		Admin:     admin,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+		Links:     []string{},
+	}
+	pipe := r.TxPipeline()
+	pipe.HSet(database.Ctx, recordKey(rec.ID), map[string]interface{}{
+		"url":        rec.URL,
+		"scope":      rec.Scope,
+		"admin":      rec.Admin,
+		"reason":     rec.Reason,
+		"created_at": rec.CreatedAt.Unix(),
+	})
+	pipe.ZAdd(database.Ctx, indexKey, &redis.Z{Score: float64(rec.CreatedAt.Unix()), Member: rec.ID})
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return nil, err
+	}
+
+	for _, link := range matches {
+		if link.Status == models.LinkDisabled {
+			continue
+		}
+		if err := links.Disable(r, link.ID, rec.ID, reason); err != nil {
+			return rec, err
+		}
+		if err := r.SAdd(database.Ctx, linksKey(rec.ID), link.ID).Err(); err != nil {
+			return rec, err
+		}
+		rec.Links = append(rec.Links, link.ID)
+		if link.Owner != "" {
+			err := notify.Push(link.Owner, notify.Notification{
+				Type:    "link_disabled",
+				Message: fmt.Sprintf("Your short link %s was disabled: %s", link.ID, reason),
+				Data:    map[string]string{"short": link.ID, "url": link.URL, "takedown": rec.ID},
+			})
+			if err != nil {
+				log.Println("failed to notify", link.Owner+":", err)
+			}
+		}
+	}
+	return rec, nil
+}
+
+// Get loads a takedown and the links it disabled.
+func Get(r *redis.Client, id string) (*Record, error) {
+	fields, err := r.HGetAll(database.Ctx, recordKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, redis.Nil
+	}
+	ids, err := r.SMembers(database.Ctx, linksKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{
+		ID:        id,
+		URL:       fields["url"],
+		Scope:     fields["scope"],
+		Admin:     fields["admin"],
+		Reason:    fields["reason"],
+		CreatedAt: unix(fields["created_at"]),
+		UndoneAt:  unix(fields["undone_at"]),
+		UndoneBy:  fields["undone_by"],
+		Links:     ids,
+	}
+	return rec, nil
+}
+
+// List returns takedowns newest first, offset/limit paged.
+func List(r *redis.Client, offset, limit int64) ([]*Record, error) {
+	ids, err := r.ZRevRange(database.Ctx, indexKey, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Record, 0, len(ids))
+	for _, id := range ids {
+		rec, err := Get(r, id)
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// MigrateIndex moves the index from its old bare key into indexKey, merging
+// with anything recorded since, and reports how many takedowns it moved. A
+// short link that has since claimed the old key is left alone.
+func MigrateIndex(r *redis.Client) (int64, error) {
+	kind, err := r.Type(database.Ctx, legacyIndexKey).Result()
+	if err != nil || kind != "zset" {
+		return 0, err
+	}
+	n, err := r.ZCard(database.Ctx, legacyIndexKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	_, err = r.TxPipelined(database.Ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZUnionStore(database.Ctx, indexKey, &redis.ZStore{Keys: []string{indexKey, legacyIndexKey}, Aggregate: "MAX"})
+		pipe.Del(database.Ctx, legacyIndexKey)
+		return nil
+	})
+	return n, err
+}
+
+// Undo restores every link a takedown disabled that hasn't been taken down
+// again since, and returns the IDs it restored.
+func Undo(r *redis.Client, id, admin string) ([]string, error) {
+	rec, err := Get(r, id)
+	if err != nil {
+		return nil, err
+	}
+	restored := []string{}
+	for _, shortID := range rec.Links {
+		ok, err := links.Restore(r, shortID, id)
+		if err != nil {
+			return restored, err
+		}
+		if ok {
+			restored = append(restored, shortID)
+		}
+	}
+	err = r.HSet(database.Ctx, recordKey(id), "undone_at", time.Now().Unix(), "undone_by", admin).Err()
+	return restored, err
+}
+
+func unix(s string) time.Time {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0).UTC()
+}
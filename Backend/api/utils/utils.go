@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -21,7 +24,7 @@ func IsDifferentDomain(url string) bool {
 }
 
 func EnsureHTTPPrefix(url string) string {
-	if !strings.HasPrefix(url, "http://") || !strings.HasPrefix(url, "https://") {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		return "http://" + url
 	}
 	return url
@@ -37,3 +40,26 @@ func GenerateToken(email string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtsecret)
 }
+
+// IsAdmin reports whether email belongs to a registered admin (DB 3).
+func IsAdmin(email string) bool {
+	if email == "" {
+		return false
+	}
+	r := database.CreateClient(3)
+	defer r.Close()
+	n, err := r.Exists(database.Ctx, "admin:"+email).Result()
+	return err == nil && n == 1
+}
+
+// CreateAdmin registers admin in DB 3. It reports false, changing nothing,
+// when the email is already an admin.
+func CreateAdmin(admin models.Admin) (bool, error) {
+	data, err := json.Marshal(admin)
+	if err != nil {
+		return false, err
+	}
+	r := database.CreateClient(3)
+	defer r.Close()
+	return r.SetNX(database.Ctx, "admin:"+admin.Email, data, 0).Result()
+}
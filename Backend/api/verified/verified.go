@@ -0,0 +1,278 @@
+// Package verified stores admin verifications of scam URLs in DB 3, one
+// record per URL with who verified it, when and why.
+//
+// Each URL has a "verified:url:<key>" hash (key being its canonical key) and
+// is listed in the "verified:index" sorted set by verification time, so
+// reads page through the index instead of loading every admin. Every
+// verification and revocation is appended to the "verified:audit" list, and
+// "verified:version" counts changes so readers can tell when the set moved.
+package verified
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/canonical"
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	IndexKey   = "verified:index"
+	AuditKey   = "verified:audit"
+	VersionKey = "verified:version"
+)
+
+func recordKey(key string) string {
+	return "verified:url:" + key
+}
+
+// Audit actions.
+const (
+	ActionVerify = "verify"
+	ActionRevoke = "revoke"
+)
+
+// Record is one verified URL.
+type Record struct {
+	URL        string    `json:"url"`
+	Admin      string    `json:"admin"`
+	Reason     string    `json:"reason,omitempty"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// Event is one entry in the audit trail.
+type Event struct {
+	Action string    `json:"action"`
+	URL    string    `json:"url"`
+	Admin  string    `json:"admin"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Add verifies url on behalf of admin. It reports false, leaving the
+// existing record alone, when url is already verified.
+func Add(r3 *redis.Client, url, admin, reason string) (*Record, bool, error) {
+	at := time.Now().UTC()
+	url, err := canonical.URL(url)
+	if err != nil {
+		return nil, false, err
+	}
+	key := canonical.Key(url)
+	rec := &Record{URL: url, Admin: admin, Reason: reason, VerifiedAt: at}
+	event, err := json.Marshal(Event{Action: ActionVerify, URL: url, Admin: admin, Reason: reason, At: at})
+	if err != nil {
+		return nil, false, err
+	}
+	added, err := r3.Eval(database.Ctx, addScript,
+		[]string{recordKey(key), IndexKey, AuditKey, VersionKey},
+		key, url, admin, reason, at.Unix(), event,
+	).Int()
+	if err != nil {
+		return nil, false, err
+	}
+	if added == 0 {
+		existing, err := Get(r3, url)
+		return existing, false, err
+	}
+	return rec, true, nil
+}
+
+// The record, index entry, audit event and version bump are written together
+// so readers never see one without the others.
+const addScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "url", ARGV[2], "admin", ARGV[3], "reason", ARGV[4], "verified_at", ARGV[5])
+redis.call("ZADD", KEYS[2], ARGV[5], ARGV[1])
+redis.call("LPUSH", KEYS[3], ARGV[6])
+redis.call("INCR", KEYS[4])
+return 1`
+
+// Revoke withdraws the verification of url, recording admin and reason in
+// the audit trail. It reports false when url wasn't verified.
+func Revoke(r3 *redis.Client, url, admin, reason string) (bool, error) {
+	canonicalURL, err := canonical.URL(url)
+	if err != nil {
+		return false, err
+	}
+	key := canonical.Key(canonicalURL)
+	event, err := json.Marshal(Event{Action: ActionRevoke, URL: canonicalURL, Admin: admin, Reason: reason, At: time.Now().UTC()})
+	if err != nil {
+		return false, err
+	}
+	n, err := r3.Eval(database.Ctx, revokeScript,
+		[]string{recordKey(key), IndexKey, AuditKey, VersionKey},
+		key, event,
+	).Int()
+	return n == 1, err
+}
+
+const revokeScript = `
+if redis.call("DEL", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("LPUSH", KEYS[3], ARGV[2])
+redis.call("INCR", KEYS[4])
+return 1`
+
+// Get returns the record for url, or redis.Nil if it isn't verified.
+func Get(r3 *redis.Client, url string) (*Record, error) {
+	fields, err := r3.HGetAll(database.Ctx, recordKey(canonical.Key(url))).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, redis.Nil
+	}
+	return decode(fields), nil
+}
+
+func decode(fields map[string]string) *Record {
+	at, _ := strconv.ParseInt(fields["verified_at"], 10, 64)
+	return &Record{
+		URL:        fields["url"],
+		Admin:      fields["admin"],
+		Reason:     fields["reason"],
+		VerifiedAt: time.Unix(at, 0).UTC(),
+	}
+}
+
+// Page returns up to limit records, most recently verified first, starting
+// offset records in, and how many there are in all.
+func Page(r3 *redis.Client, offset, limit int64) ([]*Record, int64, error) {
+	pipe := r3.Pipeline()
+	total := pipe.ZCard(database.Ctx, IndexKey)
+	keys := pipe.ZRevRange(database.Ctx, IndexKey, offset, offset+limit-1)
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return nil, 0, err
+	}
+	records, err := load(r3, keys.Val())
+	return records, total.Val(), err
+}
+
+// All returns every record, oldest first.
+func All(r3 *redis.Client) ([]*Record, error) {
+	keys, err := r3.ZRange(database.Ctx, IndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	return load(r3, keys)
+}
+
+func load(r3 *redis.Client, keys []string) ([]*Record, error) {
+	records := make([]*Record, 0, len(keys))
+	if len(keys) == 0 {
+		return records, nil
+	}
+	pipe := r3.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HGetAll(database.Ctx, recordKey(key))
+	}
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return nil, err
+	}
+	for _, cmd := range cmds {
+		if len(cmd.Val()) > 0 {
+			records = append(records, decode(cmd.Val()))
+		}
+	}
+	return records, nil
+}
+
+// Audit returns up to limit audit events, newest first, starting offset
+// events in, and how many there are in all.
+func Audit(r3 *redis.Client, offset, limit int64) ([]Event, int64, error) {
+	pipe := r3.Pipeline()
+	total := pipe.LLen(database.Ctx, AuditKey)
+	page := pipe.LRange(database.Ctx, AuditKey, offset, offset+limit-1)
+	if _, err := pipe.Exec(database.Ctx); err != nil {
+		return nil, 0, err
+	}
+	events := make([]Event, 0, len(page.Val()))
+	for _, val := range page.Val() {
+		var e Event
+		if json.Unmarshal([]byte(val), &e) == nil {
+			events = append(events, e)
+		}
+	}
+	return events, total.Val(), nil
+}
+
+// Version returns the change counter, 0 before the first verification.
+func Version(r3 *redis.Client) (int64, error) {
+	v, err := r3.Get(database.Ctx, VersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return v, err
+}
+
+// Updated returns the time of the latest verification or revocation, zero
+// if there has been none.
+func Updated(r3 *redis.Client) (time.Time, error) {
+	events, _, err := Audit(r3, 0, 1)
+	if err != nil || len(events) == 0 {
+		return time.Time{}, err
+	}
+	return events[0].At, nil
+}
+
+// MigrationReport summarises a MigrateAdmins run.
+type MigrationReport struct {
+	Admins   int `json:"admins"`
+	Migrated int `json:"urls_migrated"`
+	Already  int `json:"urls_already_verified"`
+	Invalid  int `json:"urls_invalid"`
+}
+
+// MigrateAdmins moves the URLs in every admin record's VerifiedURLs into the
+// index, attributed to that admin, and empties the arrays. URLs that don't
+// parse are left where they are. It is safe to run more than once.
+func MigrateAdmins(r3 *redis.Client) (MigrationReport, error) {
+	var report MigrationReport
+	iter := r3.Scan(database.Ctx, 0, "admin:*", 100).Iterator()
+	for iter.Next(database.Ctx) {
+		key := iter.Val()
+		val, err := r3.Get(database.Ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		var admin models.Admin
+		if err := json.Unmarshal([]byte(val), &admin); err != nil || len(admin.VerifiedURLs) == 0 {
+			continue
+		}
+		report.Admins++
+		left := []string{}
+		for _, url := range admin.VerifiedURLs {
+			if _, err := canonical.URL(url); err != nil {
+				report.Invalid++
+				left = append(left, url)
+				continue
+			}
+			_, added, err := Add(r3, url, admin.Email, "Migrated from admin record")
+			if err != nil {
+				return report, err
+			}
+			if added {
+				report.Migrated++
+			} else {
+				report.Already++
+			}
+		}
+		admin.VerifiedURLs = left
+		out, err := json.Marshal(admin)
+		if err != nil {
+			return report, err
+		}
+		if err := r3.Set(database.Ctx, key, out, 0).Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, iter.Err()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/abdulhameedsk/URL-Shortner/api/database"
+	"github.com/abdulhameedsk/URL-Shortner/api/importer"
+	"github.com/abdulhameedsk/URL-Shortner/api/links"
+	"github.com/abdulhameedsk/URL-Shortner/api/models"
+	"github.com/abdulhameedsk/URL-Shortner/api/scams"
+	"github.com/abdulhameedsk/URL-Shortner/api/takedown"
+	"github.com/abdulhameedsk/URL-Shortner/api/utils"
+	"github.com/abdulhameedsk/URL-Shortner/api/verified"
+)
+
+// runCommand handles one-shot maintenance subcommands, e.g.
+//
+//	go run . migrate links
+//	go run . migrate canonical
+//	go run . migrate reviews
+//	go run . migrate verified
+//	go run . migrate takedowns
+//	go run . migrate expiry
+//	go run . import phishtank ./online-valid.json
+//	go run . admin add ops@example.com "Ops Team"
+func runCommand(args []string) {
+	switch {
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "links":
+		r := database.CreateClient(0)
+		defer r.Close()
+		report, err := links.MigrateLegacy(r)
+		printReport(report)
+		if err != nil {
+			log.Fatal("link migration failed: ", err)
+		}
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "canonical":
+		r2 := database.CreateClient(2)
+		defer r2.Close()
+		r3 := database.CreateClient(3)
+		defer r3.Close()
+		report, err := scams.Canonicalize(r2, r3)
+		printReport(report)
+		if err != nil {
+			log.Fatal("scam record migration failed: ", err)
+		}
+		r := database.CreateClient(0)
+		defer r.Close()
+		reindexed, err := links.Reindex(r)
+		printReport(reindexed)
+		if err != nil {
+			log.Fatal("link reindex failed: ", err)
+		}
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "reviews":
+		r2 := database.CreateClient(2)
+		defer r2.Close()
+		queued, err := scams.RebuildQueue(r2)
+		printReport(map[string]int{"queued": queued})
+		if err != nil {
+			log.Fatal("review queue rebuild failed: ", err)
+		}
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "verified":
+		r3 := database.CreateClient(3)
+		defer r3.Close()
+		report, err := verified.MigrateAdmins(r3)
+		printReport(report)
+		if err != nil {
+			log.Fatal("verified scam migration failed: ", err)
+		}
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "takedowns":
+		r := database.CreateClient(0)
+		defer r.Close()
+		moved, err := takedown.MigrateIndex(r)
+		printReport(map[string]int64{"moved": moved})
+		if err != nil {
+			log.Fatal("takedown index migration failed: ", err)
+		}
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "expiry":
+		r := database.CreateClient(0)
+		defer r.Close()
+		indexed, err := links.IndexExpiries(r)
+		printReport(map[string]int{"indexed": indexed})
+		if err != nil {
+			log.Fatal("link expiry index migration failed: ", err)
+		}
+	case len(args) == 3 && args[0] == "import":
+		r2 := database.CreateClient(2)
+		defer r2.Close()
+		result, err := importer.File(r2, args[1], args[2])
+		printReport(result)
+		if err != nil {
+			log.Fatal("import failed: ", err)
+		}
+	case len(args) >= 3 && len(args) <= 4 && args[0] == "admin" && args[1] == "add":
+		// The admin API only lets admins add admins, so the first comes from here
+		admin := models.Admin{Email: args[2], Name: args[2]}
+		if len(args) == 4 {
+			admin.Name = args[3]
+		}
+		added, err := utils.CreateAdmin(admin)
+		if err != nil {
+			log.Fatal("adding admin failed: ", err)
+		}
+		printReport(map[string]interface{}{"email": admin.Email, "added": added})
+	default:
+		fmt.Fprintln(os.Stderr, "usage: myapp [migrate links|migrate canonical|migrate reviews|migrate verified|migrate takedowns|migrate expiry|import <phishtank|openphish|urlhaus> <file>|admin add <email> [name]]")
+		os.Exit(2)
+	}
+}
+
+func printReport(report interface{}) {
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+}
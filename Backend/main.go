@@ -5,10 +5,13 @@ import (
 	"os"
 	"time"
 
+	"github.com/abdulhameedsk/URL-Shortner/api/analytics"
+	"github.com/abdulhameedsk/URL-Shortner/api/hashlist"
 	"github.com/abdulhameedsk/URL-Shortner/api/middleware"
 	"github.com/abdulhameedsk/URL-Shortner/api/routes/Scam"
 	"github.com/abdulhameedsk/URL-Shortner/api/routes/User"
 	"github.com/abdulhameedsk/URL-Shortner/api/routes/shorten"
+	"github.com/abdulhameedsk/URL-Shortner/api/slugs"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -19,6 +22,11 @@ func main() {
 		log.Println(".env not loaded (continuing):", err)
 	}
 
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
 	// Gin with logger & recovery
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery())
@@ -34,6 +42,8 @@ func main() {
 	}))
 
 	setupRouters(router)
+	analytics.Start()
+	hashlist.Start()
 
 	port := os.Getenv("APP_PORT")
 	if port == "" {
@@ -48,11 +58,24 @@ func setupRouters(router *gin.Engine) {
 	router.POST("/signup", User.Signup)
 	router.POST("/login", User.Login)
 
+	// Short links resolve at the root so they can be followed from a browser
+	router.GET("/:shortID", shorten.Redirect)
+	router.HEAD("/:shortID", shorten.Redirect)
+	router.POST("/:shortID", shorten.UnlockLink) // password-protected links
+	router.POST("/:shortID/continue", shorten.ContinueLink)
+
 	// Public URL & scam routes
-	router.POST("/api/v1", shorten.ShortenURL)
+	router.POST("/api/v1", middleware.OptionalJWTAuthMiddleware(), shorten.ShortenURL) // records the owner when logged in
+	router.POST("/api/v1/bulk", middleware.OptionalJWTAuthMiddleware(), shorten.BulkShortenURL)
 	router.GET("/api/v1/:shortID", shorten.GetByShortID)
 	router.GET("/api/v1/getVerifiedScams", Scam.GetVerifiedScams)
 	router.GET("/api/v1/GetScams", Scam.GetScams)
+	router.GET("/api/v1/reports", middleware.OptionalJWTAuthMiddleware(), Scam.GetScamReports)
+	router.GET("/api/v1/feed/:format", Scam.GetFeed) // hosts, domains, csv, adblock, rpz or stix
+	router.GET("/api/v1/check", Scam.CheckURL)
+	router.POST("/api/v1/check", Scam.CheckURLs) // batch, up to 500 URLs
+	router.GET("/api/v1/hashes/prefixes", Scam.GetHashPrefixes)
+	router.GET("/api/v1/hashes/full", Scam.GetFullHashes)
 
 	// Protected (JWT)
 	protected := router.Group("/api/v1")
@@ -60,8 +83,41 @@ func setupRouters(router *gin.Engine) {
 	protected.PUT("/:shortID", shorten.EditURL)      // fixed path
 	protected.DELETE("/:shortID", shorten.DeleteURL) // fixed path
 	protected.POST("/addTag", shorten.AddTag)
-	protected.POST("/addAdmin", Scam.AddAdmin)
+	protected.GET("/links", shorten.ListLinks)
+	protected.GET("/links/:shortID/stats", shorten.LinkStats)
+	protected.GET("/notifications", User.GetNotifications)
+	protected.POST("/addAdmin", middleware.AdminOnly(), Scam.AddAdmin)
 	protected.POST("/AddScams", Scam.AddScam)
 	protected.POST("/vote", Scam.Vote)
-	protected.POST("/verifyScamByAdmin", Scam.VerifyScamByAdmin)
+	protected.POST("/verifyScamByAdmin", middleware.AdminOnly(), Scam.VerifyScamByAdmin)
+
+	// Admin only (JWT + registered admin)
+	admin := protected.Group("/admin")
+	admin.Use(middleware.AdminOnly())
+	admin.GET("/reserved", shorten.GetReservedSlugs)
+	admin.POST("/reserved", shorten.AddReservedSlug)
+	admin.DELETE("/reserved/:slug", shorten.RemoveReservedSlug)
+	admin.GET("/moderation", shorten.GetHeldLinks)
+	admin.POST("/moderation/:shortID/approve", shorten.ApproveLink)
+	admin.POST("/moderation/:shortID/reject", shorten.RejectLink)
+	admin.GET("/takedowns", Scam.GetTakedowns)
+	admin.GET("/takedowns/:id", Scam.GetTakedown)
+	admin.POST("/takedowns/:id/undo", Scam.UndoTakedown)
+	admin.POST("/links/:shortID/restore", shorten.RestoreLink)
+	admin.GET("/reviews", Scam.GetReviewQueue)
+	admin.POST("/reviews/claim", Scam.ClaimReport)
+	admin.POST("/reviews/verify", Scam.VerifyReport)
+	admin.POST("/reviews/reject", Scam.RejectReport)
+	admin.POST("/verified/revoke", Scam.RevokeVerification)
+	admin.GET("/verified/audit", Scam.GetVerificationAudit)
+	admin.GET("/scam-entries", Scam.GetScamEntries)
+	admin.POST("/scam-entries", Scam.AddScamEntry)
+	admin.DELETE("/scam-entries/:id", Scam.RemoveScamEntry)
+	admin.POST("/imports", Scam.ImportFeed)
+	admin.GET("/brands", Scam.GetBrands)
+	admin.POST("/brands", Scam.AddBrand)
+	admin.DELETE("/brands/:domain", Scam.RemoveBrand)
+
+	// Custom shorts may not shadow any of the paths above
+	slugs.RegisterRoutes(router.Routes())
 }